@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// benchmarkSink keeps the benchmarked results alive so the compiler cannot
+// optimize the calls away.
+var benchmarkSink struct {
+	valid bool
+	cpf   string
+}
+
+// runBenchmark handles the hidden "benchmark" command, which reports how many
+// CPFs per second this machine can generate and validate.
+func runBenchmark(command string, args []string, stdout, stderr io.Writer) int {
+	duration := time.Second
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--duration="):
+			value := strings.TrimPrefix(arg, "--duration=")
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return failf(stderr, command, err, msg("invalid_duration"), value)
+			}
+			duration = d
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			return failf(stderr, command, err, msg("unknown_option"), arg)
+		}
+	}
+
+	ops, elapsed, err := benchmarkLoop(duration, func() error {
+		generated, err := cpf.GenerateCPF(true, false)
+		benchmarkSink.cpf = generated
+		return err
+	})
+	if err != nil {
+		return failf(stderr, command, err, msg("generate_error"), err)
+	}
+	printBenchmark(stdout, "GenerateCPF", ops, elapsed)
+
+	ops, elapsed, _ = benchmarkLoop(duration, func() error {
+		benchmarkSink.valid = cpf.ValidateCPF("529.982.247-25", false)
+		return nil
+	})
+	printBenchmark(stdout, "ValidateCPF", ops, elapsed)
+	return 0
+}
+
+// benchmarkLoop calls fn repeatedly for about d and returns how many calls
+// were made and how long they took.
+func benchmarkLoop(d time.Duration, fn func() error) (int, time.Duration, error) {
+	ops := 0
+	start := time.Now()
+	for time.Since(start) < d {
+		// Check the clock every 1000 calls to keep its cost out of the result
+		for i := 0; i < 1000; i++ {
+			if err := fn(); err != nil {
+				return ops, time.Since(start), err
+			}
+			ops++
+		}
+	}
+	return ops, time.Since(start), nil
+}
+
+func printBenchmark(w io.Writer, name string, ops int, elapsed time.Duration) {
+	fmt.Fprintf(w, "%-12s %10d ops in %v (%.0f ops/sec)\n", name, ops, elapsed.Round(time.Millisecond), float64(ops)/elapsed.Seconds())
+}
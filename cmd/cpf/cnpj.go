@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cnpj"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// cnpjResult represents the result of a CNPJ validation
+type cnpjResult struct {
+	CNPJ     string `json:"cnpj"`
+	Valid    bool   `json:"valid,omitempty"`
+	Original string `json:"original,omitempty"`
+}
+
+// runCNPJ handles the "cnpj" command and its validate/format/generate
+// subcommands, mirroring the CPF commands.
+func runCNPJ(command string, args []string, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		printUsageLine(stdout, "Usage: cpf cnpj [validate|format|generate] [options]")
+		return 1
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "validate", "-v":
+		inputs, code := cnpjInputs(command, args[1:], stdout, stderr)
+		if code != 0 {
+			return code
+		}
+		if inputs == nil {
+			err := fmt.Errorf("missing CNPJ to validate")
+			return failf(stderr, command, err, "%s", msg("missing_cnpj_validate"))
+		}
+		results := make([]cnpjResult, 0, len(inputs))
+		for _, input := range inputs {
+			results = append(results, cnpjResult{
+				CNPJ:     input,
+				Valid:    cnpj.ValidateCNPJ(input, false),
+				Original: input,
+			})
+		}
+		output, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		fmt.Fprintln(stdout, string(output))
+
+	case "format", "-f":
+		inputs, code := cnpjInputs(command, args[1:], stdout, stderr)
+		if code != 0 {
+			return code
+		}
+		if inputs == nil {
+			err := fmt.Errorf("missing CNPJ to format")
+			return failf(stderr, command, err, "%s", msg("missing_cnpj_format"))
+		}
+		for _, input := range inputs {
+			formatted, err := cnpj.FormatCNPJ(input)
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			fmt.Fprintln(stdout, formatted)
+		}
+
+	case "generate", "-g":
+		invalid := false
+		unformatted := false
+		count := 1
+		separator := "\n"
+
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "--invalid":
+				invalid = true
+			case arg == "--unformatted":
+				unformatted = true
+			case strings.HasPrefix(arg, "--count="):
+				countStr := strings.TrimPrefix(arg, "--count=")
+				n, err := strconv.Atoi(countStr)
+				if err != nil || n <= 0 {
+					return failf(stderr, command, err, msg("invalid_count"), countStr)
+				}
+				count = n
+			case strings.HasPrefix(arg, "--separator="):
+				separator = strings.TrimPrefix(arg, "--separator=")
+			default:
+				err := fmt.Errorf("unknown option '%s'", arg)
+				return failf(stderr, command, err, msg("unknown_option"), arg)
+			}
+		}
+
+		cnpjs := make([]string, 0, count)
+		for i := 0; i < count; i++ {
+			generated, err := cnpj.GenerateCNPJ(!unformatted, invalid)
+			if err != nil {
+				return failf(stderr, command, err, msg("cnpj_generate_error"), err)
+			}
+			cnpjs = append(cnpjs, generated)
+		}
+		fmt.Fprint(stdout, strings.Join(cnpjs, separator))
+		if separator == "\n" {
+			fmt.Fprintln(stdout)
+		}
+
+	default:
+		printUsageLine(stdout, "Usage: cpf cnpj [validate|format|generate] [options]")
+		return 1
+	}
+	return 0
+}
+
+// cnpjInputs returns the CNPJs given to cnpj validate or format: the
+// argument, the lines of --file=FILE or those piped through stdin, or nil
+// when there are none. A non-zero exit code means it reported an error.
+func cnpjInputs(command string, args []string, stdout, stderr io.Writer) ([]string, int) {
+	input := ""
+	filename := ""
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsageLine(stdout, "Usage: cpf cnpj [validate|format] <cnpj> [--file=FILE]")
+			return nil, 1
+		default:
+			input = arg
+		}
+	}
+
+	var lines []string
+	var err error
+	switch {
+	case filename != "":
+		lines, err = cpf.ReadLines(filename, cpf.ProcessOptions{})
+	case input != "":
+		return []string{input}, 0
+	case stdinIsPiped():
+		lines, err = cpf.ReadLinesFrom(stdin, cpf.ProcessOptions{})
+	default:
+		return nil, 0
+	}
+	if err != nil {
+		return nil, fail(stderr, command, err)
+	}
+	return lines, 0
+}
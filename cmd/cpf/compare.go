@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runCompare handles the "compare" command. It prints "equal" and exits 0
+// when both CPFs have the same digits, and otherwise prints "different" and
+// exits 1.
+func runCompare(command string, args []string, stdout, stderr io.Writer) int {
+	const usage = "Usage: cpf compare <cpf> <cpf>"
+	var inputs []string
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsageLine(stdout, usage)
+			return 1
+		}
+		inputs = append(inputs, arg)
+	}
+
+	if len(inputs) != 2 {
+		err := fmt.Errorf("expected 2 CPFs to compare, got %d", len(inputs))
+		failf(stderr, command, err, "%s", msg("compare_count"))
+		printUsageLine(stdout, usage)
+		return 1
+	}
+
+	if cpf.CPFEqual(inputs[0], inputs[1]) {
+		fmt.Fprintln(stdout, "equal")
+		return 0
+	}
+	fmt.Fprintln(stdout, "different")
+	telemetry.Track(command, false, fmt.Errorf("CPFs differ"), nil)
+	return 1
+}
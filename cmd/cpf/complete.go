@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runComplete handles the "complete" command, which appends the check digits
+// to the first nine digits of a CPF.
+func runComplete(command string, args []string, stdout, stderr io.Writer) int {
+	const usage = "Usage: cpf complete <first 9 digits> [--unformatted]"
+	input := ""
+	formatted := true
+
+	for _, arg := range args {
+		switch {
+		case arg == "--unformatted":
+			formatted = false
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsageLine(stdout, usage)
+			return 1
+		default:
+			if input == "" {
+				input = arg
+			}
+		}
+	}
+
+	var inputs []string
+	switch {
+	case input != "":
+		inputs = []string{input}
+	case stdinIsPiped():
+		lines, err := cpf.ReadLinesFrom(stdin, cpf.ProcessOptions{})
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		inputs = lines
+	default:
+		err := fmt.Errorf("missing digits to complete")
+		failf(stderr, command, err, "%s", msg("missing_complete"))
+		printUsageLine(stdout, usage)
+		return 1
+	}
+
+	for _, in := range inputs {
+		completed, err := cpf.CompleteCPF(in, formatted)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		fmt.Fprintln(stdout, completed)
+	}
+	return 0
+}
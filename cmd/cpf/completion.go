@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completionCommand describes a command for the shell completion scripts.
+// Flags ending in "=" take a value.
+type completionCommand struct {
+	name    string
+	aliases []string
+	desc    string
+	words   []string
+	flags   []string
+}
+
+// completionCommands lists every command and its options. Keep it in sync
+// with the option parsing of each command.
+var completionCommands = []completionCommand{
+	{name: "validate", aliases: []string{"-v"}, desc: "Validate CPF(s)",
+		flags: []string{"--strict", "--fail-fast", "--watch", "--group-by-region", "--expect-region=", "--max-errors=", "--json", "--cpf=", "--file=", "--output=", "--sort", "--sort=", "--output-dir=", "--append", "--compact", "--envelope", "--template=", "--limit=", "--workers=", "--only=", "--summary", "--summary-only", "--with-line-numbers", "--format=", "--delimiter=", "--input-encoding=", "--skip-header", "--input-format=", "--column="}},
+	{name: "format", aliases: []string{"-f"}, desc: "Format CPF(s) to ###.###.###-##",
+		flags: []string{"--cpf=", "--group-sep=", "--dv-sep=", "--file=", "--output=", "--sort", "--sort=", "--append", "--compact", "--envelope", "--template=", "--format=", "--delimiter=", "--input-encoding=", "--skip-header", "--input-format=", "--column="}},
+	{name: "generate", aliases: []string{"-g"}, desc: "Generate random CPF(s)",
+		flags: []string{"--invalid", "--invalid-rate=", "--unformatted", "--json", "--json-object", "--allow-duplicates", "--stats", "--count=", "--min-count=", "--max-count=", "--region=", "--exclude-regions=", "--prefix=", "--pattern=", "--from=", "--to=", "--rate=", "--chunk-size=", "--verify", "--seed=", "--separator=", "--output=", "--append", "--compact", "--envelope", "--template=", "--format=", "--output-format=", "--table=", "--column="}},
+	{name: "normalize", desc: "Canonicalize CPF(s)",
+		flags: []string{"--unformatted", "--file=", "--output=", "--sort", "--sort=", "--append", "--compact", "--envelope", "--template=", "--format=", "--delimiter=", "--input-encoding=", "--skip-header"}},
+	{name: "reformat", desc: "Convert CPFs to formatted or digits-only",
+		flags: []string{"--to=", "--file=", "--output=", "--delimiter=", "--input-encoding=", "--skip-header"}},
+	{name: "dedupe", desc: "Remove duplicate CPFs",
+		flags: []string{"--unformatted", "--count", "--file=", "--output=", "--delimiter=", "--input-encoding=", "--skip-header"}},
+	{name: "diff", desc: "Compare two CPF files",
+		flags: []string{"--old=", "--new=", "--output="}},
+	{name: "verify", desc: "Check that every CPF in a file is valid",
+		flags: []string{"--file=", "--delimiter=", "--input-encoding=", "--skip-header"}},
+	{name: "count", desc: "Count valid and invalid CPFs",
+		flags: []string{"--file=", "--delimiter=", "--input-encoding=", "--skip-header"}},
+	{name: "extract", desc: "Find CPFs in free text",
+		flags: []string{"--file=", "--include-invalid"}},
+	{name: "complete", desc: "Append the check digits to a CPF",
+		flags: []string{"--unformatted"}},
+	{name: "mask", desc: "Redact the middle digits of CPF(s)",
+		flags: []string{"--file=", "--visible-start=", "--visible-end=", "--mask-char="}},
+	{name: "parse", desc: "Print the components of a CPF as JSON"},
+	{name: "compare", desc: "Check if two CPFs have the same digits"},
+	{name: "hash", desc: "Print a salted hash of CPF(s)",
+		flags: []string{"--file=", "--salt="}},
+	{name: "cnpj", desc: "Validate, format or generate CNPJs",
+		words: []string{"validate", "format", "generate"},
+		flags: []string{"--invalid", "--unformatted", "--count=", "--separator="}},
+	{name: "serve", desc: "Start the HTTP server",
+		flags: []string{"--addr="}},
+	{name: "telemetry", desc: "Configure telemetry settings",
+		words: []string{"enable", "disable", "status", "configure", "preview", "reset-id"},
+		flags: []string{"--endpoint=", "--api-key="}},
+	{name: "schema", desc: "Print the JSON Schema of the output"},
+	{name: "selftest", desc: "Check the CPF algorithm against known CPFs"},
+	{name: "completion", desc: "Print a shell completion script",
+		words: []string{"bash", "zsh", "fish"}},
+	{name: "version", aliases: []string{"-V"}, desc: "Show version information"},
+	{name: "help", aliases: []string{"-h", "--help"}, desc: "Show the help message"},
+}
+
+// fileFlags are the options whose value is a file path.
+var fileFlags = map[string]bool{"file": true, "output": true, "old": true, "new": true}
+
+// globalFlags are accepted before or after any command.
+var globalFlags = []string{"--quiet", "-q", "--verbose", "--no-telemetry", "--lang="}
+
+// runCompletion handles the "completion" command.
+func runCompletion(command string, args []string, stdout, stderr io.Writer) int {
+	const usage = "Usage: cpf completion [bash|zsh|fish]"
+	if len(args) != 1 {
+		printUsageLine(stdout, usage)
+		return 1
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "bash":
+		writeBashCompletion(stdout)
+	case "zsh":
+		writeZshCompletion(stdout)
+	case "fish":
+		writeFishCompletion(stdout)
+	default:
+		err := fmt.Errorf("unsupported shell '%s'", args[0])
+		failf(stderr, command, err, msg("unsupported_shell"), args[0])
+		printUsageLine(stdout, usage)
+		return 1
+	}
+	return 0
+}
+
+// completionNames returns the names and aliases of every command.
+func completionNames() []string {
+	var names []string
+	for _, c := range completionCommands {
+		names = append(names, c.name)
+		names = append(names, c.aliases...)
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# bash completion for cpf")
+	fmt.Fprintln(w, "_cpf() {")
+	fmt.Fprintln(w, `  local cur cmd word opts`)
+	fmt.Fprintln(w, `  cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `  cmd=""`)
+	fmt.Fprintln(w, `  for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do`)
+	fmt.Fprintln(w, `    case "$word" in`)
+	fmt.Fprintln(w, `      --quiet|-q|--verbose|--no-telemetry|--lang=*) ;;`)
+	fmt.Fprintln(w, `      *) cmd="$word"; break ;;`)
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `  done`)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  opts=%q\n", strings.Join(append(completionNames(), globalFlags...), " "))
+	fmt.Fprintln(w, `  case "$cmd" in`)
+	for _, c := range completionCommands {
+		words := append(append([]string{}, c.words...), c.flags...)
+		words = append(words, globalFlags...)
+		fmt.Fprintf(w, "    %s) opts=%q ;;\n", strings.Join(append([]string{c.name}, c.aliases...), "|"), strings.Join(words, " "))
+	}
+	fmt.Fprintln(w, `  esac`)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `  COMPREPLY=( $(compgen -W "$opts" -- "$cur") )`)
+	fmt.Fprintln(w, `  if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == *= ]]; then`)
+	fmt.Fprintln(w, `    compopt -o nospace`)
+	fmt.Fprintln(w, `  fi`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _cpf cpf")
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef cpf")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "_cpf() {")
+	fmt.Fprintln(w, "  local -a commands opts globalFlags")
+	fmt.Fprintf(w, "  globalFlags=(%s)\n", strings.Join(globalFlags, " "))
+	fmt.Fprintln(w, "  commands=(")
+	for _, c := range completionCommands {
+		fmt.Fprintf(w, "    '%s:%s'\n", c.name, c.desc)
+	}
+	fmt.Fprintln(w, "  )")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  local cmd=${${words[2,CURRENT-1]:#(--quiet|-q|--verbose|--no-telemetry|--lang=*)}[1]}")
+	fmt.Fprintln(w, `  if [[ -z $cmd ]]; then`)
+	fmt.Fprintln(w, "    _describe 'command' commands")
+	fmt.Fprintf(w, "    compadd -- %s\n", strings.Join(globalFlags, " "))
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "  case $cmd in")
+	for _, c := range completionCommands {
+		words := append(append([]string{}, c.words...), c.flags...)
+		fmt.Fprintf(w, "    %s) opts=(%s) ;;\n", strings.Join(append([]string{c.name}, c.aliases...), "|"), strings.Join(words, " "))
+	}
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "  compadd -S '' -- ${(M)opts:#*=}")
+	fmt.Fprintln(w, "  compadd -- ${opts:#*=}")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `if [[ "$funcstack[1]" == "_cpf" ]]; then`)
+	fmt.Fprintln(w, `  _cpf "$@"`)
+	fmt.Fprintln(w, "else")
+	fmt.Fprintln(w, "  compdef _cpf cpf")
+	fmt.Fprintln(w, "fi")
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for cpf")
+	fmt.Fprintln(w, "complete -c cpf -f")
+	fmt.Fprintln(w, "complete -c cpf -s q -l quiet -d 'Print nothing but results'")
+	fmt.Fprintln(w, "complete -c cpf -l verbose -d 'Print how long the command took'")
+	fmt.Fprintln(w, "complete -c cpf -l no-telemetry -d 'Disable telemetry for this run'")
+	fmt.Fprintln(w, "complete -c cpf -l lang -xa 'pt en' -d 'Language of messages'")
+	for _, c := range completionCommands {
+		fmt.Fprintf(w, "complete -c cpf -n __fish_use_subcommand -a %s -d '%s'\n", c.name, c.desc)
+	}
+	for _, c := range completionCommands {
+		condition := "__fish_seen_subcommand_from " + strings.Join(append([]string{c.name}, c.aliases...), " ")
+		for _, word := range c.words {
+			fmt.Fprintf(w, "complete -c cpf -n '%s' -a %s\n", condition, word)
+		}
+		for _, flag := range c.flags {
+			name, takesValue := strings.CutSuffix(strings.TrimPrefix(flag, "--"), "=")
+			switch {
+			case takesValue && fileFlags[name]:
+				fmt.Fprintf(w, "complete -c cpf -n '%s' -l %s -rF\n", condition, name)
+			case takesValue:
+				fmt.Fprintf(w, "complete -c cpf -n '%s' -l %s -r\n", condition, name)
+			default:
+				fmt.Fprintf(w, "complete -c cpf -n '%s' -l %s\n", condition, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runCount handles the "count" command, which prints only the summary of
+// validating a file or stdin as JSON.
+func runCount(command string, args []string, stdout, stderr io.Writer) int {
+	filename := ""
+	var opts cpf.ProcessOptions
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+
+	var results []cpf.CPFResult
+	var err error
+	switch {
+	case filename != "":
+		results, err = cpf.ProcessFileWithOptions(filename, opts, cpf.ValidateProcessor)
+	case stdinIsPiped():
+		results, err = cpf.ProcessReaderWithOptions(stdin, opts, cpf.ValidateProcessor)
+	default:
+		err := fmt.Errorf("missing CPFs to count")
+		failf(stderr, command, err, "%s", msg("missing_count"))
+		printUsage(stdout)
+		return 1
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	processed = len(results)
+
+	output, err := json.MarshalIndent(cpf.Summarize(results), "", "  ")
+	if err != nil {
+		return fail(stderr, command, msgErrorf("marshal_json_error", err))
+	}
+	fmt.Fprintln(stdout, string(output))
+	return 0
+}
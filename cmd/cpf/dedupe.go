@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runDedupe handles the "dedupe" command.
+func runDedupe(command string, args []string, stdout, stderr io.Writer) int {
+	filename := ""
+	outputFile := ""
+	var opts cpf.ProcessOptions
+	formatted := true
+	countOnly := false
+
+	for _, arg := range args {
+		switch {
+		case arg == "--unformatted":
+			formatted = false
+		case arg == "--count":
+			countOnly = true
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+
+	var lines []string
+	var err error
+	switch {
+	case filename != "":
+		lines, err = cpf.ReadLines(filename, opts)
+	case stdinIsPiped():
+		lines, err = cpf.ReadLinesFrom(stdin, opts)
+	default:
+		err := fmt.Errorf("missing CPFs to dedupe")
+		failf(stderr, command, err, "%s", msg("missing_dedupe"))
+		printUsage(stdout)
+		return 1
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	processed = len(lines)
+
+	unique, dupes := cpf.Dedupe(lines)
+	if countOnly {
+		fmt.Fprintf(stdout, "unique=%d duplicates=%d\n", len(unique), dupes)
+		return 0
+	}
+
+	var out strings.Builder
+	for _, line := range unique {
+		// Lines that aren't 11 digits long are kept as they are
+		normalized := cpf.NormalizeDigitsProcessor(line)
+		if formatted {
+			normalized = cpf.NormalizeProcessor(line)
+		}
+		out.WriteString(normalized.CPF + "\n")
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(out.String()), 0644); err != nil {
+			return fail(stderr, command, msgErrorf("write_file_error", err))
+		}
+		return 0
+	}
+	fmt.Fprint(stdout, out.String())
+	return 0
+}
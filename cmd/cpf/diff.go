@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runDiff handles the "diff" command.
+func runDiff(command string, args []string, stdout, stderr io.Writer) int {
+	oldFile := ""
+	newFile := ""
+	outputFile := ""
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--old="):
+			oldFile = strings.TrimPrefix(arg, "--old=")
+		case strings.HasPrefix(arg, "--new="):
+			newFile = strings.TrimPrefix(arg, "--new=")
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+
+	if oldFile == "" || newFile == "" {
+		err := fmt.Errorf("missing files to compare")
+		failf(stderr, command, err, "%s", msg("missing_diff"))
+		printUsage(stdout)
+		return 1
+	}
+
+	oldCPFs, err := cpf.ReadLines(oldFile, cpf.ProcessOptions{})
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	newCPFs, err := cpf.ReadLines(newFile, cpf.ProcessOptions{})
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+
+	output, err := json.MarshalIndent(cpf.DiffCPFs(oldCPFs, newCPFs), "", "  ")
+	if err != nil {
+		return fail(stderr, command, msgErrorf("marshal_json_error", err))
+	}
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, output, 0644); err != nil {
+			return fail(stderr, command, msgErrorf("write_file_error", err))
+		}
+		return 0
+	}
+	fmt.Fprintln(stdout, string(output))
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runExtract handles the "extract" command.
+func runExtract(command string, args []string, stdout, stderr io.Writer) int {
+	filename := ""
+	includeInvalid := false
+
+	for _, arg := range args {
+		switch {
+		case arg == "--include-invalid":
+			includeInvalid = true
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+
+	var lines []string
+	var err error
+	switch {
+	case filename != "":
+		lines, err = cpf.ReadLines(filename, cpf.ProcessOptions{})
+	case stdinIsPiped():
+		lines, err = cpf.ReadLinesFrom(stdin, cpf.ProcessOptions{})
+	default:
+		err := fmt.Errorf("missing text to extract CPFs from")
+		failf(stderr, command, err, "%s", msg("missing_extract"))
+		printUsage(stdout)
+		return 1
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+
+	text := strings.Join(lines, "\n")
+	found := cpf.ExtractCPFs(text)
+	if includeInvalid {
+		found = cpf.ExtractCandidates(text)
+	}
+	for _, c := range found {
+		fmt.Fprintln(stdout, c)
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runFormat handles the "format" command.
+func runFormat(command string, args []string, stdout, stderr io.Writer) int {
+	input := ""
+	cpfFlag := ""
+	filename := ""
+	outputFile := ""
+	appendMode := false
+	compact := false
+	envelope := false
+	sortOrder := ""
+	tmpl := ""
+	var opts cpf.ProcessOptions
+	inputFormat := "lines"
+	column := "cpf"
+	format := ""
+	groupSep := "."
+	dvSep := "-"
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		case strings.HasPrefix(arg, "--input-format="):
+			inputFormat = strings.TrimPrefix(arg, "--input-format=")
+			if inputFormat != "lines" && inputFormat != "csv" {
+				err := fmt.Errorf("invalid input format '%s'", inputFormat)
+				return failf(stderr, command, err, msg("invalid_input_format"), inputFormat)
+			}
+		case strings.HasPrefix(arg, "--column="):
+			column = strings.TrimPrefix(arg, "--column=")
+		case strings.HasPrefix(arg, "--group-sep="):
+			groupSep = strings.TrimPrefix(arg, "--group-sep=")
+		case strings.HasPrefix(arg, "--dv-sep="):
+			dvSep = strings.TrimPrefix(arg, "--dv-sep=")
+		case strings.HasPrefix(arg, "--cpf="):
+			cpfFlag = strings.TrimPrefix(arg, "--cpf=")
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case arg == "--append":
+			appendMode = true
+		case arg == "--compact":
+			compact = true
+		case arg == "--envelope":
+			envelope = true
+		case arg == "--sort":
+			sortOrder = "asc"
+		case strings.HasPrefix(arg, "--sort="):
+			sortOrder = strings.TrimPrefix(arg, "--sort=")
+			if sortOrder != "asc" && sortOrder != "desc" {
+				err := fmt.Errorf("invalid sort value '%s'", sortOrder)
+				return failf(stderr, command, err, msg("invalid_sort"), sortOrder)
+			}
+		case strings.HasPrefix(arg, "--template="):
+			tmpl = strings.TrimPrefix(arg, "--template=")
+			if err := parseTemplate(tmpl); err != nil {
+				return fail(stderr, command, err)
+			}
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "--format="):
+			f, err := parseFormat(strings.TrimPrefix(arg, "--format="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			format = f
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		default:
+			if input == "" {
+				input = arg
+			}
+		}
+	}
+	if tmpl != "" && (format != "" || compact) {
+		return fail(stderr, command, msgErrorf("template_conflict"))
+	}
+	if envelope {
+		f, err := envelopeFormat(format, compact, appendMode, tmpl)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if appendMode {
+		f, err := appendFormat(format, outputFile)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if compact {
+		f, err := compactFormat(format)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if cpfFlag != "" {
+		if input != "" {
+			err := fmt.Errorf("CPF given both as an argument and with --cpf")
+			return failf(stderr, command, err, "%s", msg("cpf_conflict"))
+		}
+		input = cpfFlag
+	}
+
+	var results []cpf.CPFResult
+	var err error
+	if inputFormat == "csv" && filename == "" {
+		return fail(stderr, command, msgErrorf("csv_requires_file"))
+	}
+
+	processor := cpf.CustomFormatProcessor(groupSep, dvSep)
+	switch {
+	case inputFormat == "csv":
+		results, err = cpf.ProcessCSV(filename, column, opts, processor)
+	case filename != "":
+		results, err = cpf.ProcessFileWithOptions(filename, opts, processor)
+	case input != "":
+		// Single CPF formatting prints the bare formatted CPF
+		formatted, err := cpf.FormatCPFCustom(input, groupSep, dvSep)
+		if err != nil {
+			n := len(cpf.UnformatCPF(input))
+			if n < 11 {
+				return failf(stderr, command, err, msg("length_short"), n, 11-n)
+			}
+			return failf(stderr, command, err, msg("length_long"), n, n-11)
+		}
+		fmt.Fprintln(stdout, formatted)
+		return 0
+	case stdinIsPiped():
+		// Format CPFs piped through stdin
+		results, err = cpf.ProcessReaderWithOptions(stdin, opts, processor)
+	default:
+		err := fmt.Errorf("missing CPF to format")
+		failf(stderr, command, err, "%s", msg("missing_format"))
+		printUsage(stdout)
+		return 1
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	processed = len(results)
+
+	clearLineNumbers(results)
+	if sortOrder != "" {
+		cpf.SortResults(results, sortOrder == "desc")
+	}
+
+	if tmpl != "" {
+		err = writeTemplate(stdout, results, tmpl, outputFile, appendMode)
+	} else {
+		err = writeResults(stdout, results, outputFile, format, appendMode)
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	return 0
+}
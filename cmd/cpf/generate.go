@@ -0,0 +1,619 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runGenerate handles the "generate" command.
+func runGenerate(command string, args []string, stdout, stderr io.Writer) int {
+	invalid := false
+	unformatted := false
+	count := 1
+	countSet := false
+	minCount, maxCount := 0, 0
+	separator := "\n"
+	useJSON := false
+	jsonObject := false
+	format := ""
+	outputFile := ""
+	appendMode := false
+	compact := false
+	envelope := false
+	tmpl := ""
+	region := -1
+	var excludeRegions []int
+	allowDuplicates := false
+	stats := false
+	verify := false
+	invalidRate := -1.0
+	prefix := ""
+	pattern := ""
+	from, to := -1, -1
+	rate := 0
+	chunkSize := 0
+	table := ""
+	column := ""
+	generator := cpf.NewGenerator(nil)
+
+	for _, arg := range args {
+		switch {
+		case arg == "--invalid":
+			invalid = true
+		case arg == "--unformatted":
+			unformatted = true
+		case arg == "--json":
+			useJSON = true
+		case arg == "--json-object":
+			jsonObject = true
+		case arg == "--allow-duplicates":
+			allowDuplicates = true
+		case arg == "--stats":
+			stats = true
+		case arg == "--verify":
+			verify = true
+		case strings.HasPrefix(arg, "--count="):
+			countStr := strings.TrimPrefix(arg, "--count=")
+			n, err := strconv.Atoi(countStr)
+			if err != nil || n < 0 {
+				return failf(stderr, command, err, msg("invalid_generate_count"), countStr)
+			}
+			count = n
+			countSet = true
+		case strings.HasPrefix(arg, "--min-count="), strings.HasPrefix(arg, "--max-count="):
+			name, value, _ := strings.Cut(arg, "=")
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return failf(stderr, command, err, msg("invalid_count_range"), strings.TrimPrefix(name, "--"), value)
+			}
+			if name == "--min-count" {
+				minCount = n
+			} else {
+				maxCount = n
+			}
+		case strings.HasPrefix(arg, "--region="):
+			regionStr := strings.TrimPrefix(arg, "--region=")
+			n, err := strconv.Atoi(regionStr)
+			if err != nil || n < 0 || n > 9 {
+				return failf(stderr, command, err, msg("invalid_region"), regionStr)
+			}
+			region = n
+		case strings.HasPrefix(arg, "--exclude-regions="):
+			regionsStr := strings.TrimPrefix(arg, "--exclude-regions=")
+			excludeRegions = nil
+			for _, field := range strings.Split(regionsStr, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(field))
+				if err != nil || n < 0 || n > 9 {
+					return failf(stderr, command, err, msg("invalid_exclude_regions"), regionsStr)
+				}
+				excludeRegions = append(excludeRegions, n)
+			}
+			if _, err := cpf.GenerateCPFExcludingRegions(excludeRegions, false); err != nil {
+				return failf(stderr, command, err, msg("invalid_exclude_regions_reason"), regionsStr, err)
+			}
+		case strings.HasPrefix(arg, "--invalid-rate="):
+			rateStr := strings.TrimPrefix(arg, "--invalid-rate=")
+			rate, err := strconv.ParseFloat(rateStr, 64)
+			if err != nil || !(rate >= 0 && rate <= 1) {
+				return failf(stderr, command, err, msg("invalid_invalid_rate"), rateStr)
+			}
+			invalidRate = rate
+		case strings.HasPrefix(arg, "--prefix="):
+			prefix = strings.TrimPrefix(arg, "--prefix=")
+			if _, err := cpf.GenerateCPFWithPrefix(prefix, false); err != nil {
+				return failf(stderr, command, err, msg("invalid_prefix"), prefix, err)
+			}
+		case strings.HasPrefix(arg, "--pattern="):
+			pattern = strings.TrimPrefix(arg, "--pattern=")
+			if _, err := cpf.GenerateCPFWithPattern(pattern, false); err != nil {
+				return failf(stderr, command, err, msg("invalid_pattern"), pattern, err)
+			}
+		case strings.HasPrefix(arg, "--from="):
+			fromStr := strings.TrimPrefix(arg, "--from=")
+			n, err := strconv.Atoi(fromStr)
+			if err != nil || n < 0 || n > 999999999 {
+				return failf(stderr, command, err, msg("invalid_from"), fromStr)
+			}
+			from = n
+		case strings.HasPrefix(arg, "--to="):
+			toStr := strings.TrimPrefix(arg, "--to=")
+			n, err := strconv.Atoi(toStr)
+			if err != nil || n < 0 || n > 999999999 {
+				return failf(stderr, command, err, msg("invalid_to_base"), toStr)
+			}
+			to = n
+		case strings.HasPrefix(arg, "--rate="):
+			rateStr := strings.TrimPrefix(arg, "--rate=")
+			n, err := strconv.Atoi(rateStr)
+			if err != nil || n <= 0 {
+				return failf(stderr, command, err, msg("invalid_rate"), rateStr)
+			}
+			rate = n
+		case strings.HasPrefix(arg, "--chunk-size="):
+			sizeStr := strings.TrimPrefix(arg, "--chunk-size=")
+			n, err := strconv.Atoi(sizeStr)
+			if err != nil || n <= 0 {
+				return failf(stderr, command, err, msg("invalid_chunk_size"), sizeStr)
+			}
+			chunkSize = n
+		case strings.HasPrefix(arg, "--seed="):
+			seedStr := strings.TrimPrefix(arg, "--seed=")
+			seed, err := strconv.ParseInt(seedStr, 10, 64)
+			if err != nil {
+				return failf(stderr, command, err, msg("invalid_seed"), seedStr)
+			}
+			generator = cpf.NewSeededGenerator(seed)
+		case strings.HasPrefix(arg, "--separator="):
+			separator = strings.TrimPrefix(arg, "--separator=")
+		case arg == "--append":
+			appendMode = true
+		case arg == "--compact":
+			compact = true
+		case arg == "--envelope":
+			envelope = true
+		case strings.HasPrefix(arg, "--template="):
+			tmpl = strings.TrimPrefix(arg, "--template=")
+			if err := parseTemplate(tmpl); err != nil {
+				return fail(stderr, command, err)
+			}
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "--table="):
+			table = strings.TrimPrefix(arg, "--table=")
+		case strings.HasPrefix(arg, "--column="):
+			column = strings.TrimPrefix(arg, "--column=")
+		case strings.HasPrefix(arg, "--format="), strings.HasPrefix(arg, "--output-format="):
+			_, value, _ := strings.Cut(arg, "=")
+			format = sqlFormat
+			if value != sqlFormat {
+				f, err := parseFormat(value)
+				if err != nil {
+					return fail(stderr, command, err)
+				}
+				format = f
+			}
+			useJSON = true
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+	if jsonObject && (useJSON || format != "" || compact || envelope || tmpl != "" || appendMode || invalidRate >= 0) {
+		return fail(stderr, command, msgErrorf("json_object_conflict"))
+	}
+	if tmpl != "" {
+		if format != "" || compact || useJSON {
+			return fail(stderr, command, msgErrorf("template_json_conflict"))
+		}
+		useJSON = true
+	}
+	if envelope {
+		f, err := envelopeFormat(format, compact, appendMode, tmpl)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+		useJSON = true
+	}
+	if appendMode {
+		f, err := appendFormat(format, outputFile)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+		useJSON = true
+	}
+	if compact {
+		f, err := compactFormat(format)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+		useJSON = true
+	}
+	if format == sqlFormat {
+		if table == "" {
+			return fail(stderr, command, msgErrorf("sql_requires_table"))
+		}
+		if column == "" {
+			column = "cpf"
+		}
+		if err := cpf.RenderSQL(nil, table, column, io.Discard); err != nil {
+			return fail(stderr, command, err)
+		}
+		if chunkSize > 0 {
+			return fail(stderr, command, msgErrorf("sql_chunk_size_conflict"))
+		}
+	} else if table != "" || column != "" {
+		return fail(stderr, command, msgErrorf("table_requires_sql"))
+	}
+	if chunkSize > 0 {
+		if outputFile == "" {
+			return fail(stderr, command, msgErrorf("chunk_size_requires_output"))
+		}
+		if appendMode || tmpl != "" || jsonObject || rate > 0 {
+			return fail(stderr, command, msgErrorf("chunk_size_conflict"))
+		}
+		useJSON = true
+	}
+
+	// With --min-count and --max-count, the count is picked at random
+	if minCount > 0 || maxCount > 0 {
+		if minCount == 0 || maxCount == 0 {
+			return fail(stderr, command, msgErrorf("min_max_together"))
+		}
+		if countSet || from >= 0 || to >= 0 || rate > 0 {
+			return fail(stderr, command, msgErrorf("min_max_conflict"))
+		}
+		n, err := generator.CountBetween(minCount, maxCount)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		count = n
+		countSet = true
+	}
+
+	if region >= 0 && invalid {
+		return fail(stderr, command, msgErrorf("region_invalid_conflict"))
+	}
+	if excludeRegions != nil && (region >= 0 || invalid || invalidRate >= 0 || prefix != "" || pattern != "") {
+		return fail(stderr, command, msgErrorf("exclude_regions_conflict"))
+	}
+	if prefix != "" && (region >= 0 || invalid || invalidRate >= 0) {
+		return fail(stderr, command, msgErrorf("prefix_conflict"))
+	}
+
+	if pattern != "" && (prefix != "" || region >= 0 || invalid || invalidRate >= 0) {
+		return fail(stderr, command, msgErrorf("pattern_conflict"))
+	}
+
+	rangeMode := from >= 0 || to >= 0
+	if rangeMode {
+		if from < 0 || to < 0 {
+			return fail(stderr, command, msgErrorf("from_to_together"))
+		}
+		if countSet || pattern != "" || prefix != "" || region >= 0 || excludeRegions != nil || invalid || invalidRate >= 0 {
+			return fail(stderr, command, msgErrorf("from_to_conflict"))
+		}
+	}
+
+	if verify && (rate > 0 || invalidRate >= 0) {
+		return fail(stderr, command, msgErrorf("verify_conflict"))
+	}
+
+	// With --rate, CPFs are written one at a time until interrupted
+	if rate > 0 {
+		if countSet || useJSON || jsonObject || stats || outputFile != "" || separator != "\n" || allowDuplicates ||
+			region >= 0 || excludeRegions != nil || prefix != "" || pattern != "" || invalidRate >= 0 || rangeMode {
+			return fail(stderr, command, msgErrorf("rate_conflict"))
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := generator.GenerateAtRate(ctx, rate, !unformatted, invalid, stdout); err != nil && !errors.Is(err, context.Canceled) {
+			return failf(stderr, command, err, msg("generate_error"), err)
+		}
+		return 0
+	}
+
+	// With --count=0, CPFs are written as fast as possible until interrupted
+	if countSet && count == 0 {
+		if useJSON || jsonObject || stats || verify || outputFile != "" || separator != "\n" || allowDuplicates ||
+			region >= 0 || excludeRegions != nil || prefix != "" || pattern != "" || invalidRate >= 0 {
+			return fail(stderr, command, msgErrorf("unbounded_conflict"))
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := generator.GenerateUntil(ctx, !unformatted, invalid, stdout); err != nil && !errors.Is(err, context.Canceled) {
+			return failf(stderr, command, err, msg("generate_error"), err)
+		}
+		return 0
+	}
+
+	if invalidRate >= 0 {
+		if region >= 0 || invalid {
+			return fail(stderr, command, msgErrorf("invalid_rate_conflict"))
+		}
+		results, err := generator.GenerateMixed(count, invalidRate, !unformatted)
+		if err != nil {
+			return failf(stderr, command, err, msg("generate_error"), err)
+		}
+		if stats {
+			defer func() {
+				cpfs := make([]string, len(results))
+				for i, r := range results {
+					cpfs[i] = r.CPF
+				}
+				printRegionStats(stderr, cpf.RegionHistogram(cpfs))
+			}()
+		}
+		if tmpl != "" {
+			if err := writeTemplate(stdout, results, tmpl, outputFile, appendMode); err != nil {
+				return fail(stderr, command, err)
+			}
+			return 0
+		}
+		if format == sqlFormat {
+			var buf bytes.Buffer
+			if err := cpf.RenderSQL(results, table, column, &buf); err != nil {
+				return fail(stderr, command, err)
+			}
+			var err error
+			switch {
+			case appendMode:
+				err = appendOutput(buf.Bytes(), outputFile)
+			case outputFile != "":
+				if err = os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+					err = msgErrorf("write_file_error", err)
+				}
+			default:
+				_, err = stdout.Write(buf.Bytes())
+			}
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			return 0
+		}
+		if chunkSize > 0 {
+			ch := make(chan cpf.CPFResult, len(results))
+			for _, r := range results {
+				ch <- r
+			}
+			close(ch)
+			if err := writeChunks(ch, outputFile, format, chunkSize); err != nil {
+				return fail(stderr, command, err)
+			}
+			return 0
+		}
+		if useJSON {
+			if err := writeResults(stdout, results, outputFile, format, appendMode); err != nil {
+				return fail(stderr, command, err)
+			}
+			return 0
+		}
+		for i, r := range results {
+			if i > 0 {
+				fmt.Fprint(stdout, separator)
+			}
+			fmt.Fprint(stdout, r.CPF)
+		}
+		if separator == "\n" {
+			fmt.Fprintln(stdout)
+		}
+		return 0
+	}
+
+	generate := func() (string, error) {
+		return generator.Generate(!unformatted, invalid)
+	}
+	if region >= 0 {
+		generate = func() (string, error) {
+			return generator.GenerateForRegion(region, !unformatted)
+		}
+	}
+	if excludeRegions != nil {
+		generate = func() (string, error) {
+			return generator.GenerateExcludingRegions(excludeRegions, !unformatted)
+		}
+	}
+	if prefix != "" {
+		generate = func() (string, error) {
+			return generator.GenerateWithPrefix(prefix, !unformatted)
+		}
+	}
+
+	if pattern != "" {
+		generate = func() (string, error) {
+			return generator.GenerateWithPattern(pattern, !unformatted)
+		}
+	}
+
+	stream := cpf.GenerateUniqueStream(count, generate)
+	if allowDuplicates {
+		stream = cpf.GenerateStream(count, generate)
+	}
+	if rangeMode {
+		stream = cpf.GenerateRangeStream(from, to, !unformatted)
+	}
+
+	if verify && !invalid {
+		stream = verifyStream(stream)
+	}
+
+	// Count the regions of the generated CPFs for --stats as they stream
+	// past, rather than keeping every CPF around
+	histogram := make(map[int]int)
+	if stats {
+		inner := stream
+		stream = func(yield func(string, error) bool) {
+			for generatedCPF, err := range inner {
+				if code, _, regionErr := cpf.Region(generatedCPF); err == nil && regionErr == nil {
+					histogram[code]++
+				}
+				if !yield(generatedCPF, err) {
+					return
+				}
+			}
+		}
+	}
+
+	if jsonObject {
+		var cpfs []string
+		for generatedCPF, err := range stream {
+			if err != nil {
+				return failf(stderr, command, err, msg("generate_error"), localize(err))
+			}
+			cpfs = append(cpfs, generatedCPF)
+		}
+
+		var buf bytes.Buffer
+		if err := cpf.WriteJSONObject(&buf, cpfs); err != nil {
+			return fail(stderr, command, err)
+		}
+		if outputFile != "" {
+			if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+				return fail(stderr, command, msgErrorf("write_file_error", err))
+			}
+		} else {
+			stdout.Write(buf.Bytes())
+		}
+		if stats {
+			printRegionStats(stderr, histogram)
+		}
+		return 0
+	}
+
+	if useJSON {
+		// Stream results into the JSON writer so memory stays flat
+		results := make(chan cpf.CPFResult)
+		done := make(chan struct{})
+		var genErr error
+		go func() {
+			defer close(results)
+			for generatedCPF, err := range stream {
+				if err != nil {
+					genErr = err
+					return
+				}
+				select {
+				case results <- cpf.GeneratedResult(generatedCPF):
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		writeStream, writeStreamTo := cpf.WriteJSONStream, cpf.WriteJSONStreamTo
+		switch format {
+		case compactJSON:
+			writeStream, writeStreamTo = cpf.WriteJSONCompactStream, cpf.WriteJSONCompactStreamTo
+		case "jsonl":
+			writeStream, writeStreamTo = cpf.WriteJSONLStream, cpf.WriteJSONLStreamTo
+		case "xml":
+			writeStream, writeStreamTo = cpf.WriteXMLStream, cpf.WriteXMLStreamTo
+		case "plain":
+			writeStream, writeStreamTo = cpf.WritePlainStream, cpf.WritePlainStreamTo
+		case sqlFormat:
+			writeStream = func(results <-chan cpf.CPFResult, outputFile string) error {
+				return cpf.RenderSQLStream(results, table, column, outputFile)
+			}
+			writeStreamTo = func(w io.Writer, results <-chan cpf.CPFResult) error {
+				return cpf.RenderSQLStreamTo(w, results, table, column)
+			}
+		case envelopeJSON:
+			// The envelope records the count up front, so it can't stream
+			writeStream = func(results <-chan cpf.CPFResult, outputFile string) error {
+				return cpf.WriteJSONEnvelope(collectResults(results), version, outputFile)
+			}
+			writeStreamTo = func(w io.Writer, results <-chan cpf.CPFResult) error {
+				return cpf.WriteJSONEnvelopeTo(w, collectResults(results), version)
+			}
+		}
+		if tmpl != "" {
+			writeStream = func(results <-chan cpf.CPFResult, outputFile string) error {
+				return cpf.RenderTemplateStream(results, tmpl, outputFile)
+			}
+			writeStreamTo = func(w io.Writer, results <-chan cpf.CPFResult) error {
+				return cpf.RenderTemplateStreamTo(w, results, tmpl)
+			}
+		}
+
+		var err error
+		switch {
+		case chunkSize > 0:
+			err = writeChunks(results, outputFile, format, chunkSize)
+		case appendMode:
+			err = appendStream(outputFile, func(w io.Writer) error {
+				return writeStreamTo(w, results)
+			})
+		case outputFile != "":
+			err = writeStream(results, outputFile)
+		default:
+			err = writeStreamTo(stdout, results)
+		}
+		close(done)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		if genErr != nil {
+			return failf(stderr, command, genErr, msg("generate_error"), localize(genErr))
+		}
+		if stats {
+			printRegionStats(stderr, histogram)
+		}
+		return 0
+	}
+
+	// Write each CPF as soon as it is generated, buffering the writes but
+	// never holding on to the CPFs themselves
+	w := bufio.NewWriter(stdout)
+	first := true
+	for generatedCPF, err := range stream {
+		if err != nil {
+			w.Flush()
+			return failf(stderr, command, err, msg("generate_error"), localize(err))
+		}
+		if !first {
+			w.WriteString(separator)
+		}
+		w.WriteString(generatedCPF)
+		first = false
+	}
+	if separator == "\n" {
+		w.WriteString("\n")
+	}
+	if err := w.Flush(); err != nil {
+		return fail(stderr, command, msgErrorf("write_output_error", err))
+	}
+	if stats {
+		printRegionStats(stderr, histogram)
+	}
+	return 0
+}
+
+// printRegionStats prints how many CPFs were issued by each fiscal region,
+// given a histogram of region codes such as RegionHistogram's.
+func printRegionStats(w io.Writer, histogram map[int]int) {
+	for code := 0; code <= 9; code++ {
+		name, _ := cpf.RegionName(code)
+		fmt.Fprintf(w, "region %d: %d (%s)\n", code, histogram[code], name)
+	}
+}
+
+// collectResults reads every result from the channel.
+func collectResults(results <-chan cpf.CPFResult) []cpf.CPFResult {
+	var collected []cpf.CPFResult
+	for r := range results {
+		collected = append(collected, r)
+	}
+	return collected
+}
+
+// verifyStream checks each CPF of stream with the validator as it passes,
+// stopping with an error at the first invalid one. It guards valid-mode
+// generation against bugs in the check digit algorithm.
+func verifyStream(stream iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for generatedCPF, err := range stream {
+			if err == nil && !cpf.ValidateCPF(generatedCPF, false) {
+				yield("", msgErrorf("verification_failed", generatedCPF))
+				return
+			}
+			if !yield(generatedCPF, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runHash handles the "hash" command, printing the salted SHA-256 digest of
+// each CPF, one per line.
+func runHash(command string, args []string, stdout, stderr io.Writer) int {
+	input := ""
+	filename := ""
+	salt := ""
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--salt="):
+			salt = strings.TrimPrefix(arg, "--salt=")
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			return failf(stderr, command, err, msg("unknown_option"), arg)
+		default:
+			input = arg
+		}
+	}
+
+	// Without a secret salt, the hash of an 11-digit CPF is trivially
+	// reversed by hashing every possible CPF
+	if salt == "" {
+		err := fmt.Errorf("missing --salt")
+		failf(stderr, command, err, "%s", msg("missing_salt"))
+		printUsageLine(stdout, "Usage: cpf hash <cpf> --salt=SECRET [--file=FILE]")
+		return 1
+	}
+
+	var inputs []cpf.CPFResult
+	switch {
+	case filename != "":
+		results, err := cpf.ProcessFile(filename, func(line string) cpf.CPFResult {
+			return cpf.CPFResult{CPF: line}
+		})
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		inputs = results
+	case input != "":
+		inputs = []cpf.CPFResult{{CPF: input}}
+	default:
+		err := fmt.Errorf("missing CPF to hash")
+		failf(stderr, command, err, "%s", msg("missing_hash"))
+		printUsage(stdout)
+		return 1
+	}
+
+	// Invalid lines of a file are reported and skipped, like reformat does
+	invalid := 0
+	for _, in := range inputs {
+		hash, err := cpf.HashCPF(in.CPF, salt)
+		if err != nil && in.Line == 0 {
+			return fail(stderr, command, err)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, msg("invalid_line")+"\n", in.Line, in.CPF)
+			invalid++
+			continue
+		}
+		fmt.Fprintln(stdout, hash)
+	}
+
+	if invalid > 0 {
+		telemetry.Track(command, false, fmt.Errorf("%d invalid CPF(s)", invalid), nil)
+		return 1
+	}
+	return 0
+}
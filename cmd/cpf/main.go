@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,268 +19,701 @@ var (
 	date    = "unknown"
 )
 
-func printVersion() {
-	fmt.Printf("CPF Tool version %s (%s) built on %s\n", version, commit, date)
-	fmt.Println("Developed by Diego Peixoto for aquarela.io")
-	fmt.Printf("Copyleft © 2024-%d\n", time.Now().Year())
+// stdin is the source for CPFs piped into the validate and format commands.
+var stdin = os.Stdin
+
+// quiet suppresses help text and messages on stderr, set by --quiet or -q.
+var quiet bool
+
+// verbose reports how long the command took on stderr, set by --verbose.
+var verbose bool
+
+// processed counts the CPFs a command read from --file or stdin, for the
+// throughput reported by --verbose.
+var processed int
+
+func printVersion(w io.Writer) {
+	fmt.Fprintf(w, "CPF Tool version %s (%s) built on %s\n", version, commit, date)
+	fmt.Fprintln(w, "Developed by Diego Peixoto for aquarela.io")
+	fmt.Fprintf(w, "Copyleft © 2024-%d\n", time.Now().Year())
 }
 
-func printHelp() {
-	help := `CPF Tool
-Developed by Diego Peixoto for aquarela.io
-Copyleft © 2024-%d
+func printHelp(w io.Writer) {
+	fmt.Fprintf(w, msg("help_header")+"\n", time.Now().Year())
 
+	help := `
 Usage:
-  cpf <command> [options]
+  cpf [--quiet] [--verbose] [--no-telemetry] [--lang=X] <command> [options]
 
 Commands:
-  validate, -v          Validate CPF(s). Use --file to validate from file.
-  format, -f <cpf>      Format a given CPF to ###.###.###-##.
+  validate, -v          Validate CPF(s). Use --file to validate from file,
+                        or pipe CPFs through stdin. A single CPF prints
+                        "valid" or "invalid"; add --json for JSON output.
+                        Several CPFs given as arguments are reported as
+                        JSON, like a file.
+  format, -f <cpf>      Format a given CPF to ###.###.###-##. Use --file to
+                        format from file, or pipe CPFs through stdin.
   generate, -g          Generate random CPF(s).
   version, -V          Show version information.
   help, -h, --help     Show this help message.
+  normalize            Canonicalize CPF(s) to ###.###.###-##. Accepts --file,
+                       --output and --unformatted (digits only).
+  reformat             Convert the valid CPFs of a file or stdin to
+                       --to=formatted (###.###.###-##) or --to=digits,
+                       reporting invalid lines on stderr.
+  dedupe               Remove duplicate CPFs from a file or stdin. Accepts
+                       --file, --output, --unformatted and --count.
+  diff                 Compare two CPF files given with --old=FILE and
+                       --new=FILE, reporting added, removed and common CPFs.
+  verify               Check that every CPF in --file=FILE is valid. Prints
+                       nothing and exits 0 if so; otherwise prints the line
+                       number of each invalid CPF, counting blank lines, and
+                       exits 1.
+  count                Print only the total, valid, invalid and malformed
+                       (not 11 digits) counts of --file or stdin as JSON.
+  extract              Find the valid CPFs in free text from --file or stdin.
+                       Add --include-invalid to keep CPF-shaped numbers whose
+                       check digits don't match.
+  complete <digits>    Append the check digits to the first 9 digits of a
+                       CPF. Add --unformatted for digits-only output.
+  mask <cpf>           Redact the middle digits of CPF(s) for logging.
+  parse <cpf>          Print the base, check digits, region, validity and
+                       formatted form of a CPF as JSON.
+  compare <a> <b>      Print "equal" and exit 0 if two CPFs have the same
+                       digits, ignoring formatting; otherwise print
+                       "different" and exit 1.
+  hash <cpf>           Print the SHA-256 digest of --salt=X and the digits
+                       of valid CPF(s), a stable pseudonym. --salt is
+                       required. Accepts --file.
+  cnpj                 Validate, format or generate CNPJs.
+  serve                Start an HTTP server exposing the CPF operations.
   telemetry            Configure telemetry settings.
+  schema               Print the JSON Schema of the JSON output.
+  selftest             Check the CPF algorithm against known CPFs, exiting
+                       with code 1 on any failure.
+  completion <shell>   Print a completion script for bash, zsh or fish.
+
+CNPJ Commands:
+  cnpj validate <cnpj>          Validate CNPJ(s). Accepts --file or stdin.
+  cnpj format <cnpj>            Format CNPJ(s) to ##.###.###/####-##. Accepts
+                                --file or stdin.
+  cnpj generate                 Generate random CNPJ(s). Accepts --invalid,
+                                --unformatted, --count and --separator.
+
+Global options:
+  --quiet, -q       Print nothing but results; rely on the exit code to
+                    signal failure.
+  --verbose         Print how long the command took to stderr, and how many
+                    lines per second it processed from a file or stdin.
+  --no-telemetry    Disable telemetry for this run without changing the
+                    saved setting.
+  --lang=X          Language of messages: pt or en (default: from LANG).
 
 Telemetry Commands:
   telemetry enable              Enable telemetry
   telemetry disable             Disable telemetry
   telemetry status              Show telemetry status
+  telemetry configure           Set --endpoint=URL and --api-key=KEY for a
+                                self-hosted PostHog instance
+  telemetry preview <command>   Run command, printing the events it would
+                                send to stderr instead of sending them
+  telemetry reset-id            Replace the anonymous ID events are sent
+                                with by a new random one
+  Set CPF_CLI_TELEMETRY=0 or DO_NOT_TRACK=1 to force telemetry off.
 
 Options for "generate":
   --invalid          Generate invalid CPF(s).
+  --invalid-rate=R  Make each CPF invalid with probability R (0-1); with
+                    --json, each entry reports whether it is valid.
   --unformatted     Generate unformatted CPF(s).
-  --count=N         Generate N CPFs (default: 1).
+  --count=N         Generate N distinct CPFs (default: 1). With 0, write
+                    CPFs until interrupted, one per line, not checked for
+                    duplicates.
+  --min-count=N --max-count=M
+                    Generate a random number of CPFs from N to M.
+  --allow-duplicates Allow the same CPF to appear more than once.
+  --stats           Print how many CPFs came from each fiscal region to
+                    stderr.
+  --seed=N          Seed the generator for reproducible output.
+  --region=N        Generate CPF(s) from fiscal region N (0-9).
+  --exclude-regions=N,M
+                    Generate CPF(s) from any fiscal region but N, M, ...
+  --prefix=DIGITS   Generate CPF(s) starting with up to 9 fixed digits.
+  --pattern=P       Generate CPF(s) whose first 9 digits follow P, where
+                    '#' or '?' is a random digit (e.g. 0000#####).
+  --from=N --to=M   Generate the CPF of every first-9-digit value from N to
+                    M, in order (e.g. --from=529982240 --to=529982250).
+  --rate=N          Write N CPFs per second, one per line, until interrupted.
   --separator=X     Separator between multiple CPFs (default: newline).
   --json            Output in JSON format.
+  --json-object     Output a JSON object mapping each index ("0", "1", ...)
+                    to a CPF.
+  --format=X        Output as json, jsonl (one JSON object per line), xml or
+                    plain (CPF and validity separated by a tab).
+  --format=sql      Write an INSERT statement per CPF for seeding a
+                    database; needs --table. --output-format=X is an alias
+                    of --format=X.
+  --table=NAME      Table of the --format=sql statements.
+  --column=NAME     Column of the --format=sql statements (default: cpf).
+  --compact         Write JSON on a single line, without indentation.
+  --envelope        Wrap JSON output with provenance; see below.
+  --template=T      Render each CPF through a template; see below.
+  --append          Add to the end of the --output file; see below.
+  --verify          Check every generated CPF with the validator and exit
+                    with an error if one is invalid (without --invalid).
+  --chunk-size=N    Split the output into files of at most N CPFs, named
+                    after --output: PREFIX-0001.json, PREFIX-0002.json, ...
+
+Options for "format":
+  --group-sep=X     Separator between the groups of digits (default: .).
+  --dv-sep=X        Separator before the check digits (default: -).
+
+Options for "mask":
+  --visible-start=N Number of leading digits left visible (default: 3).
+  --visible-end=N   Number of trailing digits left visible (default: 3).
+  --mask-char=X     Character used to hide digits (default: *).
+
+Options for "serve":
+  --addr=ADDR       Address to listen on (default: :8080).
+                    Endpoints: GET /validate?cpf=..., GET /format?cpf=...,
+                    GET /generate?count=N&invalid=true&unformatted=true
 
 File processing:
-  --file=FILE       Process CPFs from a file (one per line).
+  --cpf=CPF         Validate or format CPF, as an alternative to passing it
+                    as an argument.
+  --file=FILE       Process CPFs from a file (one per line). Gzip-compressed
+                    files are decompressed automatically. Lines longer than
+                    64 characters are reported as malformed.
+                    Repeat to validate several files; each result then
+                    records its file as "source".
+  --input-format=X  Read --file as lines (default) or csv, with a header row.
+  --column=NAME     CSV column holding the CPF, by header name or 1-based
+                    index (default: cpf). Other columns are carried through
+                    as "fields" in JSON output.
+  --delimiter=X     Split the input on X instead of newlines (e.g. "," or ";").
+  --input-encoding=X
+                    Character encoding of the input: utf8 (default) or
+                    latin1 (ISO-8859-1). Not applied to csv input.
+  --skip-header     Ignore the first non-blank line of the input, such as
+                    a "cpf" header. Not applied to csv input, whose header
+                    is always read.
+  --workers=N       Validate file CPFs concurrently using N workers.
+  --limit=N         Only validate the first N CPFs of the input.
+  --strict          Exit with code 1 if any validated CPF is invalid.
+  --watch           Validate --file again whenever it changes, printing a
+                    fresh summary each time, until interrupted.
+  --fail-fast       Stop reading --file at the first invalid CPF and exit
+                    with code 1, reporting only that CPF and its line.
+  --max-errors=N    Stop reading after N invalid CPFs and exit with code 1,
+                    reporting the CPFs read so far.
+  --only=X          Only output valid or invalid CPFs (X: valid, invalid).
+  --summary         Print total, valid and invalid counts to stderr.
+  --summary-only    Print only the counts, without the per-CPF output.
+  --with-line-numbers
+                    Report the line each CPF was read from, counting blank
+                    lines, as "line" in JSON and XML output.
+  --sort[=desc]     Sort the output by CPF digits, ascending unless desc is
+                    given, instead of keeping the input order.
   --output=FILE     Write output to a file instead of stdout.
+  --expect-region=N Also reject valid CPFs not issued by fiscal region N
+                    (0-9), with the reason "region".
+  --group-by-region Output a JSON object listing the valid CPFs of each
+                    fiscal region (0-9), with the invalid ones under
+                    "invalid".
+  --output-dir=DIR  Split validated CPFs into valid.txt, invalid.txt and
+                    malformed.txt (not 11 digits) in DIR, one per line.
+  --append          Add to the end of the --output file instead of replacing
+                    it. Output defaults to jsonl, as JSON and XML documents
+                    can't be appended to.
+  --format=X        Output format: json (default), jsonl (one compact JSON
+                    object per line), xml or plain (CPF and validity
+                    separated by a tab).
+  --compact         Write JSON on a single line, without indentation, for
+                    smaller files.
+  --envelope        Wrap JSON output in an object recording the tool version,
+                    the time of writing and the number of results.
+  --template=T      Render each result through the Go text/template T, one
+                    per line, e.g. '{{.CPF}} -> {{.Valid}}'.
 
 Examples:
   cpf -v 123.456.789-09              Validate a single CPF
+  cpf -v 123.456.789-09 --json       Validate a single CPF with JSON output
+  cpf -v 111.444.777-35 52998224725  Validate several CPFs
+  cpf validate --cpf=123.456.789-09  Validate a CPF given with a flag
   cpf validate --file=cpfs.txt       Validate CPFs from file
+  cat cpfs.txt | cpf validate        Validate CPFs from stdin
   cpf -f 12345678909                 Format a CPF
+  cpf -f 12345678909 --group-sep=" " --dv-sep=" "
+  cpf dedupe --file=cpfs.txt         Remove repeated CPFs from a file
+  cpf reformat --to=digits --file=cpfs.txt
+  cpf diff --old=jan.txt --new=feb.txt
+  cpf verify --file=cpfs.txt         Fail if any CPF in a file is invalid
+  cat email.txt | cpf extract         List the valid CPFs found in a text
+  cpf mask 52998224725               Mask a CPF as 529.***.**7-25
+  cpf parse 52998224725              Show the parts of a CPF
+  cpf compare 529.982.247-25 52998224725
+  cpf hash 52998224725 --salt=X      Pseudonymize a CPF
+  cpf complete 529982247             Complete a CPF as 529.982.247-25
   cpf -g                             Generate a CPF
   cpf -g --invalid --json            Generate invalid CPF in JSON format
+  cpf -g --region=8 --count=5        Generate 5 CPFs from São Paulo
   cpf format --file=cpfs.txt --output=formatted.json
+  cpf -g --count=100 --format=sql --table=users > seed.sql
+  cpf validate --file=cpfs.txt --template='{{.CPF}} -> {{.Valid}}'
+  cpf completion zsh > ~/.zsh/completions/_cpf
   cpf telemetry enable               Enable telemetry
   cpf telemetry disable              Disable telemetry
   cpf telemetry status               Show telemetry status`
 
-	fmt.Printf(help, time.Now().Year())
-	fmt.Println()
+	fmt.Fprintln(w, help)
 }
 
-func main() {
-	// Initialize telemetry
-	if err := telemetry.Initialize(version); err != nil {
-		// Silently continue if telemetry initialization fails
-		_ = err
+// printUsage prints the help text after a usage error, unless in quiet mode.
+func printUsage(w io.Writer) {
+	if !quiet {
+		printHelp(w)
 	}
-	// Ensure we close the telemetry client
-	defer telemetry.Close()
+}
 
-	args := os.Args[1:]
+// printUsageLine prints a short usage line after a usage error, unless in
+// quiet mode.
+func printUsageLine(w io.Writer, usage string) {
+	if !quiet {
+		fmt.Fprintln(w, usage)
+	}
+}
 
-	if len(args) == 0 {
-		printHelp()
-		os.Exit(0)
+// printTiming prints the elapsed time of a command and, when it read CPFs
+// from a file or stdin, how many it processed per second.
+func printTiming(w io.Writer, elapsed time.Duration) {
+	elapsed = elapsed.Round(time.Microsecond)
+	if processed == 0 {
+		fmt.Fprintf(w, "elapsed: %s\n", elapsed)
+		return
 	}
+	rate := float64(processed) / elapsed.Seconds()
+	fmt.Fprintf(w, "elapsed: %s, %d lines (%.0f lines/s)\n", elapsed, processed, rate)
+}
 
-	command := strings.ToLower(args[0])
+// cpfPlaceholder replaces the CPFs of the arguments sent with telemetry.
+const cpfPlaceholder = "<cpf>"
 
-	// Handle telemetry commands first
-	if command == "telemetry" {
-		if len(args) < 2 {
-			fmt.Println("Usage: cpf telemetry [enable|disable|status]")
-			os.Exit(1)
+// scrubArgs joins args for telemetry, replacing each argument that looks like
+// a CPF, and the value of --cpf, with cpfPlaceholder.
+func scrubArgs(args []string) string {
+	scrubbed := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--cpf="):
+			scrubbed[i] = "--cpf=" + cpfPlaceholder
+		case looksLikeCPF(arg):
+			scrubbed[i] = cpfPlaceholder
+		default:
+			scrubbed[i] = arg
 		}
+	}
+	return strings.Join(scrubbed, " ")
+}
 
-		switch strings.ToLower(args[1]) {
-		case "enable":
-			if err := telemetry.SetEnabled(true); err != nil {
-				fmt.Fprintf(os.Stderr, "Error enabling telemetry: %v\n", err)
-				os.Exit(1)
-			}
-			fmt.Println("Telemetry enabled")
-			return
-		case "disable":
-			if err := telemetry.SetEnabled(false); err != nil {
-				fmt.Fprintf(os.Stderr, "Error disabling telemetry: %v\n", err)
-				os.Exit(1)
-			}
-			fmt.Println("Telemetry disabled")
-			return
-		case "status":
-			if telemetry.IsEnabled() {
-				fmt.Println("Telemetry is enabled")
-			} else {
-				fmt.Println("Telemetry is disabled")
-			}
-			return
-		default:
-			fmt.Println("Usage: cpf telemetry [enable|disable|status]")
-			os.Exit(1)
+// looksLikeCPF reports whether s holds digits and nothing but the separators
+// a CPF may be written with, as whole or partial CPFs given as arguments do.
+func looksLikeCPF(s string) bool {
+	digits := 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c != '.' && c != '-' && c != '/' && c != ' ':
+			return false
 		}
 	}
+	return digits > 0
+}
 
-	// Track command execution
-	defer func() {
-		metadata := make(map[string]string)
-		if len(args) > 1 {
-			metadata["args"] = strings.Join(args[1:], " ")
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal.
+func stdinIsPiped() bool {
+	info, err := stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// fail tracks a failed command and reports err on stderr, in the current
+// language when it comes from msgErrorf. It returns the exit code commands
+// should return.
+func fail(stderr io.Writer, command string, err error) int {
+	return failf(stderr, command, err, msg("error"), localize(err))
+}
+
+// failf is like fail but reports a custom message instead of err.
+func failf(stderr io.Writer, command string, err error, format string, a ...any) int {
+	telemetry.Track(command, false, err, nil)
+	fmt.Fprintf(stderr, format+"\n", a...)
+	return 1
+}
+
+// outputFormats are the values accepted by --format.
+var outputFormats = []string{"json", "jsonl", "xml", "plain"}
+
+// sqlFormat is the output format of generate --format=sql, which writes an
+// INSERT statement per CPF. It is only accepted by generate.
+const sqlFormat = "sql"
+
+// parseFormat validates the value of a --format option.
+func parseFormat(value string) (string, error) {
+	for _, f := range outputFormats {
+		if value == f {
+			return value, nil
 		}
-		telemetry.Track(command, true, nil, metadata)
-	}()
+	}
+	return "", msgErrorf("invalid_format", value, strings.Join(outputFormats, ", "))
+}
 
-	switch command {
-	case "version":
-		printVersion()
-		return
-	case "help", "--help", "-h":
-		printHelp()
-		return
-	case "validate", "-v":
-		var results []cpf.CPFResult
-		var err error
-
-		// Check if we're processing a file
-		hasFile := false
-		for i := 1; i < len(args); i++ {
-			if strings.HasPrefix(args[i], "--file=") {
-				hasFile = true
-				filename := strings.TrimPrefix(args[i], "--file=")
-				results, err = cpf.ProcessFile(filename, cpf.ValidateProcessor)
-				if err != nil {
-					telemetry.Track(command, false, err, nil)
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					os.Exit(1)
-				}
-				break
-			}
+// parseEncoding validates the value of an --input-encoding option.
+func parseEncoding(value string) (string, error) {
+	if slices.Contains(cpf.Encodings, value) {
+		return value, nil
+	}
+	return "", msgErrorf("invalid_encoding", value, strings.Join(cpf.Encodings, ", "))
+}
+
+// clearLineNumbers drops the line numbers the file readers record, which
+// only validate --with-line-numbers prints.
+func clearLineNumbers(results []cpf.CPFResult) {
+	for i := range results {
+		results[i].Line = 0
+	}
+}
+
+// writeResults writes results in the given format to outputFile, or to stdout
+// when no output file is given. In append mode, results are added to the end
+// of outputFile instead of replacing it.
+func writeResults(stdout io.Writer, results []cpf.CPFResult, outputFile, format string, appendMode bool) error {
+	if appendMode {
+		var buf bytes.Buffer
+		if err := writeResults(&buf, results, "", format, false); err != nil {
+			return err
 		}
+		return appendOutput(buf.Bytes(), outputFile)
+	}
 
-		if !hasFile {
-			if len(args) < 2 {
-				err := fmt.Errorf("missing CPF to validate")
-				telemetry.Track(command, false, err, nil)
-				fmt.Fprintln(os.Stderr, "Error: Missing CPF to validate.")
-				printHelp()
-				os.Exit(1)
-			}
-			// Single CPF validation
-			cpfToValidate := args[1]
-			results = []cpf.CPFResult{cpf.ValidateProcessor(cpfToValidate)}
+	switch {
+	case format == envelopeJSON:
+		if outputFile != "" {
+			return cpf.WriteJSONEnvelope(results, version, outputFile)
 		}
+		return cpf.WriteJSONEnvelopeTo(stdout, results, version)
+	case format == compactJSON && outputFile != "":
+		return cpf.WriteJSONOutputCompact(results, outputFile)
+	case format == compactJSON:
+		return cpf.WriteJSONCompact(stdout, results)
+	case format == "jsonl" && outputFile != "":
+		return cpf.WriteJSONLOutput(results, outputFile)
+	case format == "jsonl":
+		return cpf.WriteJSONL(stdout, results)
+	case format == "xml" && outputFile != "":
+		return cpf.WriteXMLOutput(results, outputFile)
+	case format == "xml":
+		return cpf.WriteXML(stdout, results)
+	case format == "plain" && outputFile != "":
+		return cpf.WritePlainOutput(results, outputFile)
+	case format == "plain":
+		return cpf.WritePlain(stdout, results)
+	case outputFile != "":
+		return cpf.WriteJSONOutput(results, outputFile)
+	default:
+		return cpf.WriteJSON(stdout, results)
+	}
+}
 
-		// Check if we should output to a file
-		outputFile := ""
-		for i := 1; i < len(args); i++ {
-			if strings.HasPrefix(args[i], "--output=") {
-				outputFile = strings.TrimPrefix(args[i], "--output=")
-				break
-			}
+// writeChunks writes results in the given format across files named
+// prefix-0001.json, prefix-0002.json and so on, with at most size results
+// each.
+func writeChunks(results <-chan cpf.CPFResult, prefix, format string, size int) error {
+	ext, write := ".json", cpf.WriteJSONOutput
+	switch format {
+	case compactJSON:
+		write = cpf.WriteJSONOutputCompact
+	case envelopeJSON:
+		write = func(results []cpf.CPFResult, filename string) error {
+			return cpf.WriteJSONEnvelope(results, version, filename)
 		}
+	case "jsonl":
+		ext, write = ".jsonl", cpf.WriteJSONLOutput
+	case "xml":
+		ext, write = ".xml", cpf.WriteXMLOutput
+	case "plain":
+		ext, write = ".txt", cpf.WritePlainOutput
+	}
 
-		if err := cpf.WriteJSONOutput(results, outputFile); err != nil {
-			telemetry.Track(command, false, err, nil)
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+	w, err := cpf.NewChunkedWriter(prefix, ext, size, write)
+	if err != nil {
+		return err
+	}
+	for r := range results {
+		if err := w.Write(r); err != nil {
+			return err
 		}
+	}
+	return w.Close()
+}
 
-	case "format", "-f":
-		if len(args) < 2 {
-			err := fmt.Errorf("missing CPF to format")
-			telemetry.Track(command, false, err, nil)
-			fmt.Fprintln(os.Stderr, "Error: Missing CPF to format.")
-			printHelp()
-			os.Exit(1)
+// writeTemplate writes results rendered through the --template tmpl to
+// outputFile, or to stdout when no output file is given.
+func writeTemplate(stdout io.Writer, results []cpf.CPFResult, tmpl, outputFile string, appendMode bool) error {
+	var buf bytes.Buffer
+	if err := cpf.RenderTemplate(results, tmpl, &buf); err != nil {
+		return err
+	}
+
+	switch {
+	case appendMode:
+		return appendOutput(buf.Bytes(), outputFile)
+	case outputFile != "":
+		if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+			return msgErrorf("write_file_error", err)
 		}
-		cpfToFormat := args[1]
-		formatted, err := cpf.FormatCPF(cpfToFormat)
-		if err != nil {
-			telemetry.Track(command, false, err, nil)
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+	default:
+		if _, err := stdout.Write(buf.Bytes()); err != nil {
+			return msgErrorf("write_output_error", err)
 		}
-		fmt.Println(formatted)
+	}
+	return nil
+}
 
-	case "generate", "-g":
-		invalid := false
-		unformatted := false
-		count := 1
-		separator := "\n"
-		useJSON := false
-		outputFile := ""
-
-		for i := 1; i < len(args); i++ {
-			arg := args[i]
-			switch {
-			case arg == "--invalid":
-				invalid = true
-			case arg == "--unformatted":
-				unformatted = true
-			case arg == "--json":
-				useJSON = true
-			case strings.HasPrefix(arg, "--count="):
-				countStr := strings.TrimPrefix(arg, "--count=")
-				n, err := strconv.Atoi(countStr)
-				if err != nil || n <= 0 {
-					telemetry.Track(command, false, err, nil)
-					fmt.Fprintf(os.Stderr, "Error: Invalid count value '%s'. Must be a positive number.\n", countStr)
-					os.Exit(1)
-				}
-				count = n
-			case strings.HasPrefix(arg, "--separator="):
-				separator = strings.TrimPrefix(arg, "--separator=")
-			case strings.HasPrefix(arg, "--output="):
-				outputFile = strings.TrimPrefix(arg, "--output=")
-			default:
-				err := fmt.Errorf("unknown option '%s'", arg)
-				telemetry.Track(command, false, err, nil)
-				fmt.Fprintf(os.Stderr, "Error: Unknown option '%s'\n", arg)
-				printHelp()
-				os.Exit(1)
-			}
+// parseTemplate validates the value of a --template option.
+func parseTemplate(tmpl string) error {
+	return cpf.RenderTemplate(nil, tmpl, io.Discard)
+}
+
+// appendFlags open an output file for --append, creating it if needed.
+const appendFlags = os.O_APPEND | os.O_CREATE | os.O_WRONLY
+
+// appendOutput adds data to the end of the file at path, creating it if needed.
+func appendOutput(data []byte, path string) error {
+	return appendStream(path, func(w io.Writer) error {
+		if _, err := w.Write(data); err != nil {
+			return msgErrorf("write_file_error", err)
 		}
+		return nil
+	})
+}
 
-		if useJSON {
-			results, err := cpf.GenerateCPFsJSON(count, !unformatted, invalid)
-			if err != nil {
-				telemetry.Track(command, false, err, nil)
-				fmt.Fprintf(os.Stderr, "Error generating CPFs: %v\n", err)
-				os.Exit(1)
-			}
+// appendStream is like appendOutput but lets write stream data into the file
+// as it is produced.
+func appendStream(path string, write func(io.Writer) error) error {
+	file, err := os.OpenFile(path, appendFlags, 0644)
+	if err != nil {
+		return msgErrorf("write_file_error", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return msgErrorf("write_file_error", err)
+	}
+	return nil
+}
 
-			if err := cpf.WriteJSONOutput(results, outputFile); err != nil {
-				telemetry.Track(command, false, err, nil)
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-		} else {
-			// Generate multiple CPFs
-			cpfs := make([]string, 0, count)
-			for i := 0; i < count; i++ {
-				generatedCPF, err := cpf.GenerateCPF(!unformatted, invalid)
-				if err != nil {
-					telemetry.Track(command, false, err, nil)
-					fmt.Fprintf(os.Stderr, "Error generating CPF: %v\n", err)
-					os.Exit(1)
-				}
-				cpfs = append(cpfs, generatedCPF)
-			}
-			fmt.Print(strings.Join(cpfs, separator))
-			if separator == "\n" {
-				fmt.Println()
+// appendFormat checks the options of an --append run and returns the output
+// format to use. JSON arrays and XML documents can't be extended by appending
+// to the file, so output defaults to JSONL.
+func appendFormat(format, outputFile string) (string, error) {
+	if outputFile == "" {
+		return "", msgErrorf("append_requires_output")
+	}
+	switch format {
+	case "":
+		return "jsonl", nil
+	case "json", "xml":
+		return "", msgErrorf("append_format_conflict", format)
+	}
+	return format, nil
+}
+
+// compactJSON is the output format of --compact JSON. It isn't accepted by
+// --format.
+const compactJSON = "json-compact"
+
+// compactFormat checks the options of a --compact run and returns the output
+// format to use. JSONL is already compact and is kept as it is.
+func compactFormat(format string) (string, error) {
+	switch format {
+	case "", "json":
+		return compactJSON, nil
+	case "jsonl":
+		return format, nil
+	}
+	return "", msgErrorf("compact_conflict", format)
+}
+
+// envelopeJSON is the output format of --envelope, which wraps the JSON
+// array with the tool version and the time of writing. It isn't accepted by
+// --format.
+const envelopeJSON = "json-envelope"
+
+// envelopeFormat checks the options of an --envelope run and returns the
+// output format to use.
+func envelopeFormat(format string, compact, appendMode bool, tmpl string) (string, error) {
+	if compact || appendMode || tmpl != "" {
+		return "", msgErrorf("envelope_conflict")
+	}
+	if format != "" && format != "json" {
+		return "", msgErrorf("envelope_format_conflict", format)
+	}
+	return envelopeJSON, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command described by args, writing its output to stdout
+// and stderr, and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) (code int) {
+	// Global flags may appear anywhere and are removed before dispatch
+	quiet = false
+	verbose = false
+	processed = 0
+	lang = langFromEnv()
+	noTelemetry := false
+	var rest []string
+	for _, arg := range args {
+		switch {
+		case arg == "--quiet" || arg == "-q":
+			quiet = true
+		case arg == "--no-telemetry":
+			noTelemetry = true
+		case arg == "--verbose":
+			verbose = true
+		case strings.HasPrefix(arg, "--lang="):
+			lang = strings.TrimPrefix(arg, "--lang=")
+			if _, ok := messages[lang]; !ok {
+				fmt.Fprintf(stderr, msg("unsupported_lang")+"\n", lang)
+				return 1
 			}
+		default:
+			rest = append(rest, arg)
 		}
+	}
+	args = rest
+	if quiet {
+		stderr = io.Discard
+	}
+
+	// "telemetry preview <command>" runs the command, printing the events it
+	// would send instead of sending them
+	if len(args) >= 2 && strings.ToLower(args[0]) == "telemetry" && strings.ToLower(args[1]) == "preview" {
+		args = args[2:]
+		if len(args) == 0 {
+			printUsageLine(stdout, "Usage: cpf telemetry preview <command> [options]")
+			return 1
+		}
+		telemetry.Preview(stderr)
+		defer telemetry.Preview(nil)
+	}
+
+	// Initialize telemetry; --no-telemetry turns it off for this run only,
+	// without reading or writing the saved configuration, unless the command
+	// is one managing that configuration
+	telemetry.Suppress(noTelemetry)
+	if !noTelemetry || (len(args) > 0 && strings.ToLower(args[0]) == "telemetry") {
+		if err := telemetry.Initialize(version); err != nil {
+			// Silently continue if telemetry initialization fails
+			_ = err
+		}
+	}
+	// Ensure we close the telemetry client
+	defer telemetry.Close()
 
+	if verbose {
+		start := time.Now()
+		defer func() {
+			printTiming(stderr, time.Since(start))
+		}()
+	}
+
+	if len(args) == 0 {
+		printHelp(stdout)
+		return 0
+	}
+
+	command := strings.ToLower(args[0])
+
+	// Handle telemetry commands first
+	if command == "telemetry" {
+		return runTelemetry(args[1:], stdout, stderr)
+	}
+
+	// Track successful command execution; failures are tracked where they occur
+	defer func() {
+		if code != 0 {
+			return
+		}
+		metadata := make(map[string]string)
+		// hash arguments include the salt, which mustn't leave the machine
+		if len(args) > 1 && command != "hash" {
+			metadata["args"] = scrubArgs(args[1:])
+		}
+		telemetry.Track(command, true, nil, metadata)
+	}()
+
+	switch command {
+	case "version":
+		printVersion(stdout)
+		return 0
+	case "help", "--help", "-h":
+		printHelp(stdout)
+		return 0
+	case "validate", "-v":
+		return runValidate(command, args[1:], stdout, stderr)
+	case "format", "-f":
+		return runFormat(command, args[1:], stdout, stderr)
+	case "generate", "-g":
+		return runGenerate(command, args[1:], stdout, stderr)
+	case "normalize":
+		return runNormalize(command, args[1:], stdout, stderr)
+	case "cnpj":
+		return runCNPJ(command, args[1:], stdout, stderr)
+	case "dedupe":
+		return runDedupe(command, args[1:], stdout, stderr)
+	case "reformat":
+		return runReformat(command, args[1:], stdout, stderr)
+	case "diff":
+		return runDiff(command, args[1:], stdout, stderr)
+	case "verify":
+		return runVerify(command, args[1:], stdout, stderr)
+	case "count":
+		return runCount(command, args[1:], stdout, stderr)
+	case "extract":
+		return runExtract(command, args[1:], stdout, stderr)
+	case "mask":
+		return runMask(command, args[1:], stdout, stderr)
+	case "parse":
+		return runParse(command, args[1:], stdout, stderr)
+	case "compare":
+		return runCompare(command, args[1:], stdout, stderr)
+	case "hash":
+		return runHash(command, args[1:], stdout, stderr)
+	case "complete":
+		return runComplete(command, args[1:], stdout, stderr)
+	case "benchmark":
+		// Hidden from the help text; meant for sanity-checking performance
+		return runBenchmark(command, args[1:], stdout, stderr)
+	case "completion":
+		return runCompletion(command, args[1:], stdout, stderr)
+	case "schema":
+		return runSchema(command, args[1:], stdout, stderr)
+	case "selftest":
+		return runSelfTest(command, args[1:], stdout, stderr)
+	case "serve":
+		return runServe(command, args[1:], stdout, stderr)
 	default:
 		err := fmt.Errorf("unknown command '%s'", command)
-		telemetry.Track(command, false, err, nil)
-		fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n", command)
-		printHelp()
-		os.Exit(1)
+		failf(stderr, command, err, msg("unknown_command"), command)
+		printUsage(stdout)
+		return 1
 	}
-} 
\ No newline at end of file
+}
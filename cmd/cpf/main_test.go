@@ -0,0 +1,2055 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runCLI invokes run with args, isolating the telemetry config in a
+// temporary home directory, and returns the exit code and captured output.
+// Messages are in English unless args include --lang.
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LANG", "C")
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// runCLITracked is like runCLI with telemetry enabled, recording the events
+// run emits in memory instead of sending them.
+func runCLITracked(t *testing.T, args ...string) (int, []telemetry.Event) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+	if err := os.MkdirAll(home+"/.cpf-cli", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(home+"/.cpf-cli/telemetry.json", []byte(`{"enabled": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &telemetry.MemorySink{}
+	telemetry.SetSink(sink)
+	t.Cleanup(func() { telemetry.SetSink(nil) })
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, sink.Events()
+}
+
+// withStdin replaces stdin with a pipe carrying input for the test duration.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatalf("writing to pipe error = %v", err)
+	}
+	w.Close()
+
+	original := stdin
+	stdin = r
+	t.Cleanup(func() {
+		stdin = original
+		r.Close()
+	})
+}
+
+func decodeResults(t *testing.T, output string) []cpf.CPFResult {
+	t.Helper()
+	var results []cpf.CPFResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("decoding output %q error = %v", output, err)
+	}
+	return results
+}
+
+func TestRunNoArgsPrintsHelp(t *testing.T) {
+	code, stdout, _ := runCLI(t)
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	if !strings.Contains(stdout, "Usage:") {
+		t.Errorf("stdout = %q, want help text", stdout)
+	}
+}
+
+func TestRunVersion(t *testing.T) {
+	code, stdout, _ := runCLI(t, "version")
+	if code != 0 || !strings.Contains(stdout, "CPF Tool version dev") {
+		t.Errorf("run(version) = %d, %q", code, stdout)
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	code, _, stderr := runCLI(t, "frobnicate")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if stderr != "Error: Unknown command 'frobnicate'\n" {
+		t.Errorf("stderr = %q, want unknown command error", stderr)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"valid CPF", []string{"validate", "529.982.247-25"}, 0, "valid\n"},
+		{"invalid CPF", []string{"-v", "52998224726"}, 0, "invalid\n"},
+		{"strict valid CPF", []string{"validate", "52998224725", "--strict"}, 0, "valid\n"},
+		{"strict invalid CPF", []string{"validate", "--strict", "52998224726"}, 1, "invalid\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestRunValidateJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantCode  int
+		wantValid bool
+	}{
+		{"valid CPF", []string{"validate", "--json", "529.982.247-25"}, 0, true},
+		{"invalid CPF", []string{"-v", "52998224726", "--json"}, 0, false},
+		{"strict invalid CPF", []string{"validate", "--json", "--strict", "52998224726"}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			results := decodeResults(t, stdout)
+			if len(results) != 1 || results[0].Valid != tt.wantValid {
+				t.Errorf("results = %+v, want one result with valid=%v", results, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestRunValidateFileStrict(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--strict"); code != 0 {
+		t.Errorf("all valid file exit code = %d, want 0", code)
+	}
+
+	if err := os.WriteFile(filename, []byte("52998224725\n123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ := runCLI(t, "validate", "--file="+filename, "--strict")
+	if code != 1 {
+		t.Errorf("file with invalid CPF exit code = %d, want 1", code)
+	}
+	if results := decodeResults(t, stdout); len(results) != 2 {
+		t.Errorf("got %d results, want 2", len(results))
+	}
+}
+
+func TestRunValidateOnly(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n123\n111.444.777-35\n11111111111\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		only string
+		want []string
+	}{
+		{"valid", []string{"52998224725", "111.444.777-35"}},
+		{"invalid", []string{"123", "11111111111"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.only, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, "validate", "--file="+filename, "--only="+tt.only)
+			if code != 0 {
+				t.Fatalf("exit code = %d, want 0", code)
+			}
+			results := decodeResults(t, stdout)
+			if len(results) != len(tt.want) {
+				t.Fatalf("results = %+v, want %v", results, tt.want)
+			}
+			for i, r := range results {
+				if r.CPF != tt.want[i] {
+					t.Errorf("results[%d] = %q, want %q", i, r.CPF, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if code, _, stderr := runCLI(t, "validate", "--file="+filename, "--only=maybe"); code != 1 || !strings.Contains(stderr, "Invalid only value") {
+		t.Errorf("run(--only=maybe) = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--only=valid", "--strict"); code != 1 {
+		t.Errorf("run(--only=valid --strict) = %d, want 1 for filtered-out invalid CPFs", code)
+	}
+}
+
+func TestRunValidateWithLineNumbers(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n\n\n123\n\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stdout, _ := runCLI(t, "validate", "--file="+filename)
+	if strings.Contains(stdout, `"line"`) {
+		t.Errorf("default output contains line numbers: %s", stdout)
+	}
+
+	code, stdout, _ := runCLI(t, "validate", "--file="+filename, "--with-line-numbers")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	wantLines := []int{1, 4, 6}
+	if len(results) != len(wantLines) {
+		t.Fatalf("results = %+v, want %d entries", results, len(wantLines))
+	}
+	for i, r := range results {
+		if r.Line != wantLines[i] {
+			t.Errorf("results[%d].Line = %d, want %d", i, r.Line, wantLines[i])
+		}
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--with-line-numbers", "--workers=2"); code != 1 {
+		t.Errorf("run(--with-line-numbers --workers=2) = %d, want 1", code)
+	}
+}
+
+func TestRunValidateLongLines(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	content := "52998224725\n529.982.247-25" + strings.Repeat("x", 60) + "\n111.444.777-35\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "validate", "--file="+filename)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	want := decodeResults(t, stdout)
+	if len(want) != 3 || want[1].Valid || want[1].Reason != "length" || !strings.Contains(want[1].Error, "line too long") {
+		t.Fatalf("results = %+v, want the long line malformed", want)
+	}
+
+	// Validating with several workers reports the same results
+	code, stdout, _ = runCLI(t, "validate", "--file="+filename, "--workers=2")
+	if code != 0 {
+		t.Fatalf("run(--workers=2) exit code = %d, want 0", code)
+	}
+	if got := decodeResults(t, stdout); !reflect.DeepEqual(got, want) {
+		t.Errorf("run(--workers=2) = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunValidateFailFast(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n\n123\n111.444.777-35\n456\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "validate", "--file="+filename, "--fail-fast")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 1 || results[0].CPF != "123" || results[0].Line != 3 {
+		t.Errorf("results = %+v, want only 123 on line 3", results)
+	}
+	if stderr != "Error: Invalid CPF '123' on line 3.\n" {
+		t.Errorf("stderr = %q", stderr)
+	}
+
+	if err := os.WriteFile(filename, []byte("52998224725\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ = runCLI(t, "validate", "--file="+filename, "--fail-fast")
+	if code != 0 || len(decodeResults(t, stdout)) != 2 {
+		t.Errorf("run(--fail-fast) on a valid file = %d, %q", code, stdout)
+	}
+
+	if code, _, _ := runCLI(t, "validate", "52998224725", "--fail-fast"); code != 1 {
+		t.Errorf("run(--fail-fast) without --file = %d, want 1", code)
+	}
+}
+
+func TestRunValidateMaxErrors(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n1\n2\n111.444.777-35\n3\n4\n5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "validate", "--file="+filename, "--max-errors=2")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 3 || results[2].CPF != "2" {
+		t.Errorf("results = %+v, want the 3 CPFs read before stopping", results)
+	}
+	if stderr != "Error: Exceeded max errors: stopped after 2 invalid CPFs.\n" {
+		t.Errorf("stderr = %q", stderr)
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--max-errors=10"); code != 0 {
+		t.Errorf("run(--max-errors=10) = %d, want 0", code)
+	}
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--max-errors=0"); code != 1 {
+		t.Errorf("run(--max-errors=0) = %d, want 1", code)
+	}
+}
+
+func TestRunValidateMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, second := dir+"/first.txt", dir+"/second.txt"
+	if err := os.WriteFile(first, []byte("52998224725\n123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "validate", "--file="+first, "--file="+second)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	var results []cpf.CPFResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("output is not JSON: %q", stdout)
+	}
+	wantSources := []string{first, first, second}
+	if len(results) != len(wantSources) {
+		t.Fatalf("results = %+v, want %d entries", results, len(wantSources))
+	}
+	for i, r := range results {
+		if r.Source != wantSources[i] {
+			t.Errorf("results[%d].Source = %q, want %q", i, r.Source, wantSources[i])
+		}
+	}
+
+	_, stdout, _ = runCLI(t, "validate", "--file="+first)
+	if strings.Contains(stdout, `"source"`) {
+		t.Errorf("single file output contains sources: %s", stdout)
+	}
+}
+
+func TestRunValidateSummary(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n123\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "validate", "--file="+filename, "--summary")
+	if code != 0 || len(decodeResults(t, stdout)) != 3 {
+		t.Errorf("run(--summary) = %d, stdout %q", code, stdout)
+	}
+	if stderr != "total=3 valid=2 invalid=1\n" {
+		t.Errorf("run(--summary) stderr = %q", stderr)
+	}
+
+	code, stdout, stderr = runCLI(t, "validate", "--file="+filename, "--summary-only")
+	if code != 0 || stdout != "" || stderr != "total=3 valid=2 invalid=1\n" {
+		t.Errorf("run(--summary-only) = %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+}
+
+func TestRunCPFFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+		wantStderr string
+	}{
+		{"validate valid", []string{"validate", "--cpf=529.982.247-25"}, 0, "valid\n", ""},
+		{"validate invalid", []string{"-v", "--cpf=123"}, 0, "invalid\n", ""},
+		{"format", []string{"format", "--cpf=52998224725"}, 0, "529.982.247-25\n", ""},
+		{"validate conflict", []string{"validate", "52998224725", "--cpf=11144477735"}, 1, "", "Error: Give the CPF either as an argument or with --cpf, not both.\n"},
+		{"format conflict", []string{"format", "--cpf=52998224725", "11144477735"}, 1, "", "Error: Give the CPF either as an argument or with --cpf, not both.\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+			if stderr != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunValidateStdin(t *testing.T) {
+	withStdin(t, "52998224725\n\n123\n")
+	code, stdout, _ := runCLI(t, "validate")
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 2 || !results[0].Valid || results[1].Valid {
+		t.Errorf("results = %+v, want [valid, invalid]", results)
+	}
+}
+
+func TestRunValidateMissingCPF(t *testing.T) {
+	code, _, stderr := runCLI(t, "validate")
+	if code != 1 || !strings.Contains(stderr, "Missing CPF to validate") {
+		t.Errorf("run(validate) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunFormat(t *testing.T) {
+	code, stdout, _ := runCLI(t, "format", "52998224725")
+	if code != 0 || stdout != "529.982.247-25\n" {
+		t.Errorf("run(format) = %d, %q", code, stdout)
+	}
+
+	code, _, stderr := runCLI(t, "-f", "123")
+	if code != 1 || stderr != "Error: invalid CPF number: got 3 digits, need 11 (8 short)\n" {
+		t.Errorf("run(-f 123) = %d, stderr %q", code, stderr)
+	}
+
+	code, _, stderr = runCLI(t, "-f", "529982247250")
+	if code != 1 || stderr != "Error: invalid CPF number: got 12 digits, need 11 (1 too many)\n" {
+		t.Errorf("run(-f 529982247250) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunGenerate(t *testing.T) {
+	code, stdout, _ := runCLI(t, "generate", "--count=3", "--unformatted")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3: %q", len(lines), stdout)
+	}
+	for _, line := range lines {
+		if len(line) != 11 || !cpf.ValidateCPF(line, false) {
+			t.Errorf("generated %q, want a valid unformatted CPF", line)
+		}
+	}
+
+	code, _, stderr := runCLI(t, "-g", "--count=-1")
+	if code != 1 || !strings.Contains(stderr, "Invalid count value '-1'") {
+		t.Errorf("run(-g --count=-1) = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "-g", "--count=0", "--json"); code != 1 {
+		t.Errorf("run(-g --count=0 --json) = %d, want 1", code)
+	}
+}
+
+func TestRunFormatFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := dir + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n123\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "format", "--file="+filename)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].CPF != "529.982.247-25" || results[0].Error != "" {
+		t.Errorf("results[0] = %+v, want formatted CPF", results[0])
+	}
+	if results[1].Error == "" {
+		t.Errorf("results[1] = %+v, want an error for the short entry", results[1])
+	}
+	if results[2].CPF != "111.444.777-35" {
+		t.Errorf("results[2] = %+v, want formatted CPF", results[2])
+	}
+	if strings.Contains(stdout, `"line"`) {
+		t.Errorf("format --file output has line numbers:\n%s", stdout)
+	}
+
+	outputFile := dir + "/formatted.json"
+	if code, stdout, _ := runCLI(t, "format", "--file="+filename, "--output="+outputFile); code != 0 || stdout != "" {
+		t.Fatalf("run(format --output) = %d, stdout %q", code, stdout)
+	}
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := decodeResults(t, string(data)); len(results) != 3 {
+		t.Errorf("output file has %d results, want 3", len(results))
+	}
+}
+
+func TestRunNormalize(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("529 982 247 25\n111.444.777-35\n12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "normalize", "--file="+filename, "--unformatted")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].CPF != "52998224725" || results[1].CPF != "11144477735" {
+		t.Errorf("results = %+v, want digits-only CPFs", results)
+	}
+	if results[2].CPF != "12345" || results[2].Error == "" {
+		t.Errorf("results[2] = %+v, want the short line passed through with an error", results[2])
+	}
+	if strings.Contains(stdout, `"line"`) {
+		t.Errorf("normalize --file output has line numbers:\n%s", stdout)
+	}
+}
+
+func TestRunGenerateJSON(t *testing.T) {
+	code, stdout, _ := runCLI(t, "generate", "--json", "--count=25")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 25 {
+		t.Errorf("got %d results, want 25", len(results))
+	}
+
+	for _, invalid := range []bool{false, true} {
+		args := []string{"generate", "--json", "--count=10"}
+		if invalid {
+			args = append(args, "--invalid")
+		}
+		code, stdout, _ := runCLI(t, args...)
+		if code != 0 {
+			t.Fatalf("run(%v) exit code = %d", args, code)
+		}
+		if want := fmt.Sprintf(`"valid": %v`, !invalid); strings.Count(stdout, want) != 10 {
+			t.Errorf("run(%v) output does not mark every CPF with %s:\n%s", args, want, stdout)
+		}
+	}
+}
+
+func TestRunDedupe(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n111.444.777-35\n529.982.247-25\n529 982 247 25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "dedupe", "--file="+filename)
+	if code != 0 || stdout != "529.982.247-25\n111.444.777-35\n" {
+		t.Errorf("run(dedupe) = %d, %q", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, "dedupe", "--file="+filename, "--unformatted")
+	if code != 0 || stdout != "52998224725\n11144477735\n" {
+		t.Errorf("run(dedupe --unformatted) = %d, %q", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, "dedupe", "--file="+filename, "--count")
+	if code != 0 || stdout != "unique=2 duplicates=2\n" {
+		t.Errorf("run(dedupe --count) = %d, %q", code, stdout)
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/old.txt", []byte("52998224725\n123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/new.txt", []byte("529.982.247-25\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "diff", "--old="+dir+"/old.txt", "--new="+dir+"/new.txt")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	var got cpf.DiffResult
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", stdout, err)
+	}
+	if len(got.Added) != 1 || got.Added[0] != "111.444.777-35" ||
+		len(got.Removed) != 1 || got.Removed[0] != "123" ||
+		len(got.Common) != 1 || got.Common[0] != "529.982.247-25" {
+		t.Errorf("diff = %+v", got)
+	}
+
+	if code, _, stderr := runCLI(t, "diff", "--old="+dir+"/old.txt"); code != 1 || !strings.Contains(stderr, "Missing files") {
+		t.Errorf("run(diff --old) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunFormatJSONL(t *testing.T) {
+	withStdin(t, "52998224725\n123\n")
+	code, stdout, _ := runCLI(t, "validate", "--format=jsonl")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), stdout)
+	}
+	for i, line := range lines {
+		var r cpf.CPFResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("line %d %q is not a JSON object: %v", i, line, err)
+		}
+		if r.Valid != (i == 0) {
+			t.Errorf("line %d valid = %v", i, r.Valid)
+		}
+	}
+
+	code, stdout, _ = runCLI(t, "-g", "--count=3", "--format=jsonl")
+	if code != 0 || strings.Count(stdout, "\n") != 3 || strings.Contains(stdout, "[") {
+		t.Errorf("run(-g --format=jsonl) = %d, %q", code, stdout)
+	}
+
+	if code, _, stderr := runCLI(t, "validate", "--format=yaml", "52998224725"); code != 1 || !strings.Contains(stderr, "invalid format 'yaml'") {
+		t.Errorf("run(--format=yaml) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunValidateDelimiter(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725;123;111.444.777-35"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ := runCLI(t, "validate", "--file="+filename, "--delimiter=;")
+	results := decodeResults(t, stdout)
+	if code != 0 || len(results) != 3 || !results[0].Valid || results[1].Valid || !results[2].Valid {
+		t.Errorf("run(validate --delimiter=;) = %d, %+v", code, results)
+	}
+}
+
+func TestRunQuiet(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"invalid CPF", []string{"--quiet", "format", "123"}, 1, ""},
+		{"unknown option", []string{"validate", "--bogus", "-q"}, 1, ""},
+		{"unknown command", []string{"-q", "frobnicate"}, 1, ""},
+		{"strict invalid CPF", []string{"-q", "validate", "--strict", "123"}, 1, "invalid\n"},
+		{"valid CPF", []string{"-q", "validate", "52998224725"}, 0, "valid\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+			if stderr != "" {
+				t.Errorf("stderr = %q, want empty", stderr)
+			}
+		})
+	}
+}
+
+func TestRunLang(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		env        string
+		wantStderr string
+	}{
+		{"pt format error", []string{"--lang=pt", "format", "123"}, "", "Erro: número de CPF inválido: tem 3 dígitos, precisa de 11 (faltam 8)\n"},
+		{"en format error", []string{"format", "123", "--lang=en"}, "pt_BR.UTF-8", "Error: invalid CPF number: got 3 digits, need 11 (8 short)\n"},
+		{"pt from LANG", []string{"format", "123"}, "pt_BR.UTF-8", "Erro: número de CPF inválido: tem 3 dígitos, precisa de 11 (faltam 8)\n"},
+		{"pt unknown option", []string{"--lang=pt", "validate", "--bogus"}, "", "Erro: opção desconhecida '--bogus'\n"},
+		{"pt unknown mask option", []string{"--lang=pt", "mask", "--bogus"}, "", "Erro: opção desconhecida '--bogus'\n"},
+		{"pt option conflict", []string{"--lang=pt", "generate", "--region=1", "--invalid"}, "", "Erro: --region não pode ser combinado com --invalid\n"},
+		{"en option conflict", []string{"generate", "--region=1", "--invalid"}, "", "Error: --region cannot be combined with --invalid\n"},
+		{"pt generic error", []string{"--lang=pt", "validate", "--file=missing.txt"}, "", "Erro: "},
+		{"unsupported language", []string{"--lang=fr", "format", "123"}, "", "Error: Unsupported language 'fr'. Must be pt or en.\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("LANG", tt.env)
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != 1 {
+				t.Errorf("exit code = %d, want 1", code)
+			}
+			if !strings.HasPrefix(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+
+	t.Run("help header", func(t *testing.T) {
+		_, stdout, _ := runCLI(t, "--lang=pt", "help")
+		if !strings.HasPrefix(stdout, "Ferramenta de CPF\n") {
+			t.Errorf("help starts with %q, want the pt header", strings.SplitN(stdout, "\n", 2)[0])
+		}
+	})
+}
+
+func TestMessagesTranslated(t *testing.T) {
+	for key := range messages["en"] {
+		if _, ok := messages["pt"][key]; !ok {
+			t.Errorf("message %q has no pt translation", key)
+		}
+	}
+}
+
+func TestMsgErrorf(t *testing.T) {
+	cause := errors.New("disk full")
+	err := msgErrorf("write_file_error", cause)
+	if err.Error() != "error writing to file: disk full" || !errors.Is(err, cause) {
+		t.Errorf("msgErrorf() = %v, want the English message wrapping the cause", err)
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, "completion", shell)
+			if code != 0 || stdout == "" {
+				t.Fatalf("run(completion %s) = %d, %q", shell, code, stdout)
+			}
+			for _, c := range completionCommands {
+				if !strings.Contains(stdout, c.name) {
+					t.Errorf("completion script is missing command %q", c.name)
+				}
+			}
+			for _, flag := range []string{"strict", "allow-duplicates", "visible-start"} {
+				if !strings.Contains(stdout, flag) {
+					t.Errorf("completion script is missing flag %q", flag)
+				}
+			}
+		})
+	}
+
+	if code, _, _ := runCLI(t, "completion", "powershell"); code != 1 {
+		t.Errorf("run(completion powershell) = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateInvalidRate(t *testing.T) {
+	code, stdout, _ := runCLI(t, "-g", "--count=200", "--invalid-rate=0.5", "--seed=3", "--json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	invalid := 0
+	for _, r := range results {
+		if r.Valid != cpf.ValidateCPF(r.CPF, false) {
+			t.Errorf("result %+v has the wrong validity", r)
+		}
+		if !r.Valid {
+			invalid++
+		}
+	}
+	if len(results) != 200 || invalid == 0 || invalid == 200 {
+		t.Errorf("got %d results with %d invalid", len(results), invalid)
+	}
+
+	if code, _, stderr := runCLI(t, "-g", "--invalid-rate=1.5"); code != 1 || !strings.Contains(stderr, "Invalid invalid-rate value") {
+		t.Errorf("run(-g --invalid-rate=1.5) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunValidateCSV(t *testing.T) {
+	filename := t.TempDir() + "/people.csv"
+	if err := os.WriteFile(filename, []byte("name,document\nAna,529.982.247-25\nJoão,123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "validate", "--file="+filename, "--input-format=csv", "--column=document")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 2 || !results[0].Valid || results[1].Valid || results[1].Fields["name"] != "João" {
+		t.Errorf("results = %+v", results)
+	}
+
+	code, _, stderr := runCLI(t, "format", "--file="+filename, "--input-format=csv")
+	if code != 1 || !strings.Contains(stderr, `column "cpf" not found`) {
+		t.Errorf("run(format --input-format=csv) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	code, stdout, _ := runCLI(t, "benchmark", "--duration=10ms")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	for _, name := range []string{"GenerateCPF", "ValidateCPF"} {
+		if !strings.Contains(stdout, name) || !strings.Contains(stdout, "ops/sec") {
+			t.Errorf("output is missing the %s result: %q", name, stdout)
+		}
+	}
+
+	if code, _, _ := runCLI(t, "benchmark", "--duration=soon"); code != 1 {
+		t.Errorf("run(benchmark --duration=soon) = %d, want 1", code)
+	}
+}
+
+func TestRunGeneratePrefix(t *testing.T) {
+	code, stdout, _ := runCLI(t, "-g", "--prefix=529982", "--count=5", "--unformatted")
+	lines := strings.Fields(stdout)
+	if code != 0 || len(lines) != 5 {
+		t.Fatalf("run(-g --prefix=529982) = %d, %q", code, stdout)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "529982") || !cpf.ValidateCPF(line, false) {
+			t.Errorf("generated %q, want a valid CPF starting with 529982", line)
+		}
+	}
+
+	if code, _, stderr := runCLI(t, "-g", "--prefix=12x"); code != 1 || !strings.Contains(stderr, "Invalid prefix value") {
+		t.Errorf("run(-g --prefix=12x) = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestRunGeneratePattern(t *testing.T) {
+	code, stdout, _ := runCLI(t, "-g", "--pattern=0000#####", "--count=5", "--unformatted")
+	lines := strings.Fields(stdout)
+	if code != 0 || len(lines) != 5 {
+		t.Fatalf("run(-g --pattern=0000#####) = %d, %q", code, stdout)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "0000") || !cpf.ValidateCPF(line, false) {
+			t.Errorf("generated %q, want a valid CPF starting with 0000", line)
+		}
+	}
+
+	if code, _, stderr := runCLI(t, "-g", "--pattern=0000"); code != 1 || !strings.Contains(stderr, "Invalid pattern value") {
+		t.Errorf("run(-g --pattern=0000) = %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "-g", "--pattern=0000#####", "--prefix=1"); code != 1 {
+		t.Errorf("run(-g --pattern --prefix) = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateStats(t *testing.T) {
+	for _, args := range [][]string{
+		{"-g", "--count=50", "--stats"},
+		{"-g", "--count=50", "--stats", "--format=jsonl"},
+		{"-g", "--count=50", "--stats", "--invalid-rate=0.5"},
+	} {
+		code, _, stderr := runCLI(t, args...)
+		if code != 0 {
+			t.Fatalf("run(%v) = %d", args, code)
+		}
+		lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+		if len(lines) != 10 {
+			t.Fatalf("run(%v) stderr has %d lines, want 10:\n%s", args, len(lines), stderr)
+		}
+		total := 0
+		for _, line := range lines {
+			var code, n int
+			if _, err := fmt.Sscanf(line, "region %d: %d", &code, &n); err != nil {
+				t.Fatalf("unexpected stats line %q", line)
+			}
+			total += n
+		}
+		if total != 50 {
+			t.Errorf("run(%v) stats sum to %d, want 50", args, total)
+		}
+	}
+}
+
+func TestRunTelemetryEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantCommand string
+		wantSuccess bool
+		wantError   string
+		wantArgs    string
+	}{
+		{"validate", []string{"validate", "52998224725"}, "validate", true, "", "<cpf>"},
+		{"format alias", []string{"-f", "529.982.247-25"}, "-f", true, "", "<cpf>"},
+		{"cpf option", []string{"validate", "--cpf=52998224725", "--json"}, "validate", true, "", "--cpf=<cpf> --json"},
+		{"compare", []string{"compare", "52998224725", "529.982.247-25"}, "compare", true, "", "<cpf> <cpf>"},
+		{"other arguments", []string{"generate", "--count=2", "--region=8"}, "generate", true, "", "--count=2 --region=8"},
+		{"format error", []string{"format", "123"}, "format", false, "invalid CPF number: got 3 digits, need 11 (8 short)", ""},
+		{"strict failure", []string{"validate", "--strict", "123"}, "validate", false, "invalid CPF found", ""},
+		{"unknown command", []string{"frobnicate"}, "frobnicate", false, "unknown command 'frobnicate'", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, events := runCLITracked(t, tt.args...)
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1: %+v", len(events), events)
+			}
+			e := events[0]
+			if e.Command != tt.wantCommand || e.Success != tt.wantSuccess || e.Error != tt.wantError || e.Metadata["args"] != tt.wantArgs {
+				t.Errorf("event = %+v", e)
+			}
+		})
+	}
+
+	if _, events := runCLITracked(t, "telemetry", "status"); len(events) != 0 {
+		t.Errorf("telemetry command emitted %d events, want 0", len(events))
+	}
+}
+
+func TestRunNoTelemetry(t *testing.T) {
+	if code, events := runCLITracked(t, "--no-telemetry", "validate", "52998224725"); code != 0 || len(events) != 0 {
+		t.Errorf("run(--no-telemetry validate) = %d with %d events, want none", code, len(events))
+	}
+	if _, events := runCLITracked(t, "validate", "52998224725"); len(events) != 1 {
+		t.Errorf("run(validate) after --no-telemetry emitted %d events, want 1", len(events))
+	}
+
+	code, stdout, _ := runCLI(t, "--no-telemetry", "telemetry", "status")
+	if code != 0 || !strings.Contains(stdout, "disabled for this run") {
+		t.Errorf("run(--no-telemetry telemetry status) = %d, %q", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, "validate", "52998224725", "--no-telemetry")
+	if code != 0 || stdout != "valid\n" {
+		t.Errorf("run(validate --no-telemetry) = %d, %q", code, stdout)
+	}
+	if _, err := os.Stat(os.Getenv("HOME") + "/.cpf-cli/telemetry.json"); !os.IsNotExist(err) {
+		t.Errorf("run(validate --no-telemetry) wrote the telemetry config: %v", err)
+	}
+
+	// The flag only lasts for the run it was given to
+	code, stdout, _ = runCLI(t, "telemetry", "status")
+	if code != 0 || strings.Contains(stdout, "for this run") {
+		t.Errorf("run(telemetry status) = %d, %q", code, stdout)
+	}
+}
+
+func TestRunTelemetryConfigure(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"telemetry", "configure", "--endpoint=https://posthog.example.com", "--api-key=phc_test"}, &stdout, &stderr)
+	if code != 0 || !strings.Contains(stdout.String(), "https://posthog.example.com") {
+		t.Fatalf("run(telemetry configure) = %d, %q, %q", code, stdout.String(), stderr.String())
+	}
+
+	data, err := os.ReadFile(home + "/.cpf-cli/telemetry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved telemetry.Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Endpoint != "https://posthog.example.com" || saved.ApiKey != "phc_test" || saved.Enabled {
+		t.Errorf("saved config = %+v", saved)
+	}
+}
+
+func TestRunCNPJFile(t *testing.T) {
+	filename := t.TempDir() + "/cnpjs.txt"
+	if err := os.WriteFile(filename, []byte("11222333000181\n\n11.222.333/0001-82\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "cnpj", "validate", "--file="+filename)
+	var results []cnpjResult
+	if err := json.Unmarshal([]byte(stdout), &results); code != 0 || err != nil {
+		t.Fatalf("run(cnpj validate --file) = %d, %q", code, stdout)
+	}
+	if len(results) != 2 || !results[0].Valid || results[1].Valid {
+		t.Errorf("run(cnpj validate --file) = %+v, want the first CNPJ valid", results)
+	}
+
+	withStdin(t, "11222333000181\n11222333000182\n")
+	code, stdout, _ = runCLI(t, "cnpj", "format")
+	if code != 0 || stdout != "11.222.333/0001-81\n11.222.333/0001-82\n" {
+		t.Errorf("run(cnpj format) from stdin = %d, %q", code, stdout)
+	}
+}
+
+func TestRunExtract(t *testing.T) {
+	const text = "Cliente 529.982.247-25 e 11144477735.\nCPF errado: 123.456.789-00, de novo 52998224725.\n"
+
+	withStdin(t, text)
+	code, stdout, _ := runCLI(t, "extract")
+	if code != 0 || stdout != "529.982.247-25\n11144477735\n" {
+		t.Errorf("run(extract) = %d, %q", code, stdout)
+	}
+
+	withStdin(t, text)
+	code, stdout, _ = runCLI(t, "extract", "--include-invalid")
+	if code != 0 || stdout != "529.982.247-25\n11144477735\n123.456.789-00\n" {
+		t.Errorf("run(extract --include-invalid) = %d, %q", code, stdout)
+	}
+
+	// Lines of prose are longer than any CPF, and are read whole
+	withStdin(t, strings.Repeat("Lorem ipsum dolor sit amet. ", 5)+"O CPF do cliente é 529.982.247-25.\n")
+	code, stdout, _ = runCLI(t, "extract")
+	if code != 0 || stdout != "529.982.247-25\n" {
+		t.Errorf("run(extract) of a long line = %d, %q", code, stdout)
+	}
+}
+
+func TestRunGeneratePlainFormat(t *testing.T) {
+	for _, args := range [][]string{
+		{"-g", "--count=20", "--invalid", "--format=plain"},
+		{"-g", "--count=20", "--invalid-rate=0.5", "--format=plain"},
+	} {
+		code, stdout, _ := runCLI(t, args...)
+		if code != 0 {
+			t.Fatalf("run(%v) exit code = %d", args, code)
+		}
+		lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+		if len(lines) != 20 {
+			t.Fatalf("run(%v) printed %d lines, want 20", args, len(lines))
+		}
+		for _, line := range lines {
+			columns := strings.Split(line, "\t")
+			if len(columns) != 2 {
+				t.Fatalf("line %q does not have two tab-separated columns", line)
+			}
+			if want := strconv.FormatBool(cpf.ValidateCPF(columns[0], false)); columns[1] != want {
+				t.Errorf("line %q reports validity %s, want %s", line, columns[1], want)
+			}
+		}
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	dir := t.TempDir()
+	good := dir + "/good.txt"
+	bad := dir + "/bad.txt"
+	if err := os.WriteFile(good, []byte("529.982.247-25\n11144477735\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("529.982.247-25\n123\n11144477735\n111.111.111-11\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	blanks := dir + "/blanks.txt"
+	if err := os.WriteFile(blanks, []byte("\n529.982.247-25\n\n123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"all valid", []string{"verify", "--file=" + good}, 0, ""},
+		{"two invalid lines", []string{"verify", "--file=" + bad}, 1, "2\n4\n"},
+		{"blank lines counted", []string{"verify", "--file=" + blanks}, 1, "4\n"},
+		{"missing file option", []string{"verify", "-q"}, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestRunAppend(t *testing.T) {
+	output := t.TempDir() + "/out.jsonl"
+
+	batches := [][]string{
+		{"validate", "52998224725", "--output=" + output, "--append"},
+		{"-g", "--count=3", "--output=" + output, "--append"},
+		{"normalize", "11144477735", "--output=" + output, "--append", "--format=jsonl"},
+	}
+	for _, args := range batches {
+		if code, _, stderr := runCLI(t, args...); code != 0 {
+			t.Fatalf("run(%v) = %d, stderr %q", args, code, stderr)
+		}
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("output has %d lines, want 5:\n%s", len(lines), data)
+	}
+	for i, line := range lines {
+		var r cpf.CPFResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("line %d is not JSON: %q", i+1, line)
+		}
+	}
+	if !strings.Contains(lines[0], "52998224725") || !strings.Contains(lines[4], "111.444.777-35") {
+		t.Errorf("batches missing from output:\n%s", data)
+	}
+
+	for _, args := range [][]string{
+		{"validate", "52998224725", "--append"},
+		{"validate", "52998224725", "--output=" + output, "--append", "--format=json"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestAppendOutput(t *testing.T) {
+	path := t.TempDir() + "/out.txt"
+	for _, batch := range []string{"first\n", "second\n"} {
+		if err := appendOutput([]byte(batch), path); err != nil {
+			t.Fatalf("appendOutput() error = %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("file = %q, want both batches", data)
+	}
+}
+
+func TestRunReformat(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("529.982.247-25\n11144477735\n\n123\n111.444.777-34\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wantStderr := "line 4: invalid CPF '123'\nline 5: invalid CPF '111.444.777-34'\n"
+
+	tests := []struct {
+		to         string
+		wantOutput string
+	}{
+		{"digits", "52998224725\n11144477735\n"},
+		{"formatted", "529.982.247-25\n111.444.777-35\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, "reformat", "--to="+tt.to, "--file="+filename)
+			if code != 1 {
+				t.Errorf("exit code = %d, want 1 for the invalid lines", code)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+			if stderr != wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr, wantStderr)
+			}
+		})
+	}
+
+	withStdin(t, "52998224725\n")
+	if code, stdout, _ := runCLI(t, "reformat", "--to=formatted"); code != 0 || stdout != "529.982.247-25\n" {
+		t.Errorf("run(reformat stdin) = %d, %q", code, stdout)
+	}
+	if code, _, _ := runCLI(t, "reformat", "--to=xml", "--file="+filename); code != 1 {
+		t.Errorf("run(reformat --to=xml) = %d, want 1", code)
+	}
+}
+
+func TestRunComplete(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"formatted", []string{"complete", "529982247"}, 0, "529.982.247-25\n"},
+		{"unformatted", []string{"complete", "111.444.777", "--unformatted"}, 0, "11144477735\n"},
+		{"too short", []string{"complete", "12345678"}, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"formatted and unformatted", []string{"compare", "529.982.247-25", "52998224725"}, 0, "equal\n"},
+		{"different", []string{"compare", "529.982.247-25", "111.444.777-35"}, 1, "different\n"},
+		{"invalid but same digits", []string{"compare", "123.456.789-00", "12345678900"}, 0, "equal\n"},
+		{"one CPF", []string{"compare", "52998224725"}, 1, "Usage: cpf compare <cpf> <cpf>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestRunHash(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("529.982.247-25\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"unsalted", []string{"hash", "52998224725"}, 1, ""},
+		{"empty salt", []string{"hash", "52998224725", "--salt="}, 1, ""},
+		{"salted", []string{"hash", "529.982.247-25", "--salt=x"}, 0, "3a48f18b7d50af442121d8881028ea3b6ad69aecd58ee0b0051d7fdbf25c34e2\n"},
+		{"file", []string{"hash", "--file=" + filename, "--salt=x"}, 0, "3a48f18b7d50af442121d8881028ea3b6ad69aecd58ee0b0051d7fdbf25c34e2\n418e01547ac24a2c4aff342b33d9c09fa7bc60757fbb5b086a2f41d4a6b54dbf\n"},
+		{"invalid", []string{"hash", "52998224724"}, 1, ""},
+		{"missing CPF", []string{"hash", "--salt=x"}, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantCode == 0 && stdout != tt.wantOutput {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantOutput)
+			}
+		})
+	}
+
+	// Invalid lines are reported with their line number without stopping
+	badFile := t.TempDir() + "/bad.txt"
+	if err := os.WriteFile(badFile, []byte("529.982.247-25\n\n123\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr := runCLI(t, "hash", "--file="+badFile, "--salt=x")
+	wantOutput := "3a48f18b7d50af442121d8881028ea3b6ad69aecd58ee0b0051d7fdbf25c34e2\n418e01547ac24a2c4aff342b33d9c09fa7bc60757fbb5b086a2f41d4a6b54dbf\n"
+	if code != 1 || stdout != wantOutput || stderr != "line 3: invalid CPF '123'\n" {
+		t.Errorf("run(hash --file) = %d, %q, %q", code, stdout, stderr)
+	}
+
+	// Neither the CPFs nor the salt may reach telemetry
+	code, events := runCLITracked(t, "hash", "52998224725", "--salt=topsecret")
+	if code != 0 || len(events) != 1 {
+		t.Fatalf("run(hash) = %d with events %+v", code, events)
+	}
+	if args, ok := events[0].Metadata["args"]; ok {
+		t.Errorf("hash event args = %q, want none", args)
+	}
+}
+
+func TestRunVerbose(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n123\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantTiming string
+	}{
+		{"off by default", []string{"validate", "--file=" + filename}, ""},
+		{"single CPF", []string{"--verbose", "validate", "52998224725"}, "elapsed: "},
+		{"file", []string{"validate", "--file=" + filename, "--verbose"}, ", 3 lines ("},
+		{"quiet", []string{"--verbose", "-q", "validate", "--file=" + filename}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != 0 {
+				t.Fatalf("exit code = %d, want 0", code)
+			}
+			if strings.Contains(stdout, "elapsed") {
+				t.Errorf("timing written to stdout: %q", stdout)
+			}
+			if tt.wantTiming == "" {
+				if stderr != "" {
+					t.Errorf("stderr = %q, want empty", stderr)
+				}
+				return
+			}
+			if !strings.HasPrefix(stderr, "elapsed: ") || !strings.Contains(stderr, tt.wantTiming) {
+				t.Errorf("stderr = %q, want a timing line containing %q", stderr, tt.wantTiming)
+			}
+		})
+	}
+}
+
+func TestRunCount(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	content := "52998224725\n111.444.777-35\n\n111.444.777-34\n11111111111\n123\nabc\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runCLI(t, "count", "--file="+filename)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	var got cpf.Summary
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output is not JSON: %q", stdout)
+	}
+	if want := (cpf.Summary{Total: 6, Valid: 2, Invalid: 4, Malformed: 2}); got != want {
+		t.Errorf("count = %+v, want %+v", got, want)
+	}
+
+	// A line too long to be a CPF is malformed, even when it starts with one
+	withStdin(t, "52998224725"+strings.Repeat("x", 60)+"\n")
+	code, stdout, _ = runCLI(t, "count")
+	if err := json.Unmarshal([]byte(stdout), &got); code != 0 || err != nil {
+		t.Fatalf("run(count) of a long line = %d, %q", code, stdout)
+	}
+	if want := (cpf.Summary{Total: 1, Invalid: 1, Malformed: 1}); got != want {
+		t.Errorf("count of a long line = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunFormatSeparators(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantOutput string
+	}{
+		{"spaces", []string{"format", "52998224725", "--group-sep= ", "--dv-sep= "}, "529 982 247 25\n"},
+		{"slashes", []string{"-f", "529.982.247-25", "--group-sep=/", "--dv-sep=/"}, "529/982/247/25\n"},
+		{"dv only", []string{"-f", "52998224725", "--group-sep="}, "529982247-25\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, _ := runCLI(t, tt.args...)
+			if code != 0 || stdout != tt.wantOutput {
+				t.Errorf("run(%v) = %d, %q, want %q", tt.args, code, stdout, tt.wantOutput)
+			}
+		})
+	}
+
+	withStdin(t, "52998224725\n")
+	code, stdout, _ := runCLI(t, "format", "--group-sep= ", "--dv-sep=/")
+	if results := decodeResults(t, stdout); code != 0 || len(results) != 1 || results[0].CPF != "529 982 247/25" {
+		t.Errorf("run(format stdin) = %d, %q", code, stdout)
+	}
+}
+
+func TestRunTelemetryPreview(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "telemetry", "preview", "validate", "52998224725")
+	if code != 0 || stdout != "valid\n" {
+		t.Fatalf("run(telemetry preview validate) = %d, %q", code, stdout)
+	}
+
+	var payload struct {
+		Event      string         `json:"event"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(stderr), &payload); err != nil {
+		t.Fatalf("stderr is not a JSON payload: %v\n%s", err, stderr)
+	}
+	if payload.Event != "cli_command" || payload.Properties["command"] != "validate" || payload.Properties["args"] != "<cpf>" {
+		t.Errorf("previewed payload = %+v", payload)
+	}
+	if telemetry.IsEnabled() {
+		t.Error("telemetry still enabled after the preview")
+	}
+
+	if code, _, _ := runCLI(t, "telemetry", "preview"); code != 1 {
+		t.Errorf("run(telemetry preview) = %d, want 1", code)
+	}
+}
+
+func TestRunSchema(t *testing.T) {
+	code, stdout, _ := runCLI(t, "schema")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	var schema struct {
+		Type string `json:"type"`
+		Defs struct {
+			CPFResult struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"CPFResult"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if schema.Type != "array" {
+		t.Errorf("schema type = %q, want array", schema.Type)
+	}
+
+	// Every field of CPFResult must be described
+	properties := schema.Defs.CPFResult.Properties
+	fields := reflect.TypeOf(cpf.CPFResult{})
+	for i := 0; i < fields.NumField(); i++ {
+		name, _, _ := strings.Cut(fields.Field(i).Tag.Get("json"), ",")
+		if _, ok := properties[name]; !ok {
+			t.Errorf("schema is missing property %q", name)
+		}
+	}
+	for _, name := range []string{"cpf", "valid"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("schema is missing property %q", name)
+		}
+	}
+}
+
+func TestRunCompact(t *testing.T) {
+	for _, args := range [][]string{
+		{"validate", "52998224725", "--compact"},
+		{"normalize", "52998224725", "--compact", "--format=json"},
+		{"generate", "--count=3", "--seed=1", "--compact"},
+	} {
+		code, stdout, _ := runCLI(t, args...)
+		if code != 0 || strings.Count(stdout, "\n") != 1 || strings.Contains(stdout, "  ") {
+			t.Errorf("run(%v) = %d, %q, want single-line JSON", args, code, stdout)
+			continue
+		}
+		if results := decodeResults(t, stdout); len(results) == 0 {
+			t.Errorf("run(%v) decoded no results", args)
+		}
+	}
+
+	if code, _, _ := runCLI(t, "validate", "52998224725", "--compact", "--format=xml"); code != 1 {
+		t.Errorf("run(validate --compact --format=xml) = %d, want 1", code)
+	}
+}
+
+func TestRunTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantOutput string
+	}{
+		{"validate", []string{"validate", "52998224725", "--template={{.CPF}} -> {{.Valid}}"}, "52998224725 -> true\n"},
+		{"normalize", []string{"normalize", "52998224725", "--template=INSERT INTO cpfs VALUES ('{{.CPF}}');"}, "INSERT INTO cpfs VALUES ('529.982.247-25');\n"},
+		{"generate", []string{"generate", "--count=2", "--pattern=52998224#", "--seed=1", "--template={{len .CPF}}"}, "14\n14\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != 0 || stdout != tt.wantOutput {
+				t.Errorf("run(%v) = %d, %q (stderr %q), want %q", tt.args, code, stdout, stderr, tt.wantOutput)
+			}
+		})
+	}
+
+	for _, args := range [][]string{
+		{"validate", "52998224725", "--template={{.CPF"},
+		{"validate", "52998224725", "--template={{.CPF}}", "--json"},
+		{"format", "--file=cpfs.txt", "--template={{.CPF}}", "--format=xml"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	if code, stdout, _ := runCLI(t, "selftest"); code != 0 || stdout != "ok\n" {
+		t.Errorf("run(selftest) = %d, %q", code, stdout)
+	}
+	if code, _, _ := runCLI(t, "selftest", "--bogus"); code != 1 {
+		t.Errorf("run(selftest --bogus) = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateRange(t *testing.T) {
+	code, stdout, _ := runCLI(t, "generate", "--from=529982246", "--to=529982248", "--unformatted")
+	if code != 0 || stdout != "52998224644\n52998224725\n52998224806\n" {
+		t.Errorf("run(generate --from --to) = %d, %q", code, stdout)
+	}
+
+	for _, args := range [][]string{
+		{"generate", "--from=10", "--to=9"},
+		{"generate", "--from=10"},
+		{"generate", "--from=1", "--to=1000000000"},
+		{"generate", "--from=1", "--to=5", "--count=2"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunGenerateJSONObject(t *testing.T) {
+	code, stdout, _ := runCLI(t, "generate", "--count=11", "--json-object")
+	if code != 0 {
+		t.Fatalf("run(generate --json-object) = %d", code)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output is not a JSON object: %v\n%s", err, stdout)
+	}
+	if len(got) != 11 {
+		t.Fatalf("decoded %d keys, want 11", len(got))
+	}
+	for i := 0; i < 11; i++ {
+		if c, ok := got[strconv.Itoa(i)]; !ok || !cpf.ValidateCPF(c, true) {
+			t.Errorf("key %d = %q, %v", i, c, ok)
+		}
+	}
+
+	if code, _, _ := runCLI(t, "generate", "--json-object", "--json"); code != 1 {
+		t.Errorf("run(generate --json-object --json) = %d, want 1", code)
+	}
+}
+
+func TestRunValidateOutputDir(t *testing.T) {
+	tmp := t.TempDir()
+	input := tmp + "/cpfs.txt"
+	if err := os.WriteFile(input, []byte("52998224725\n123\n52998224724\n111.444.777-35\nabc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := tmp + "/out/split"
+	code, stdout, stderr := runCLI(t, "validate", "--file="+input, "--output-dir="+dir)
+	if code != 0 || stdout != "" {
+		t.Fatalf("run(validate --output-dir) = %d, %q (stderr %q)", code, stdout, stderr)
+	}
+
+	want := map[string]string{
+		"valid.txt":     "52998224725\n111.444.777-35\n",
+		"invalid.txt":   "52998224724\n",
+		"malformed.txt": "123\nabc\n",
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(dir + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--file="+input, "--output-dir="+dir, "--json"); code != 1 {
+		t.Errorf("run(validate --output-dir --json) = %d, want 1", code)
+	}
+}
+
+func TestRunEnvelope(t *testing.T) {
+	for _, args := range [][]string{
+		{"validate", "52998224725", "--envelope"},
+		{"generate", "--count=3", "--envelope"},
+		{"generate", "--count=3", "--invalid-rate=0.5", "--envelope"},
+	} {
+		code, stdout, stderr := runCLI(t, args...)
+		if code != 0 {
+			t.Fatalf("run(%v) = %d (stderr %q)", args, code, stderr)
+		}
+		var envelope cpf.Envelope
+		if err := json.Unmarshal([]byte(stdout), &envelope); err != nil {
+			t.Fatalf("run(%v) output is not an envelope: %v\n%s", args, err, stdout)
+		}
+		if envelope.Version != version || envelope.GeneratedAt.IsZero() || envelope.Count != len(envelope.Results) || envelope.Count == 0 {
+			t.Errorf("run(%v) envelope = %+v", args, envelope)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"validate", "52998224725", "--envelope", "--compact"},
+		{"normalize", "52998224725", "--envelope", "--format=jsonl"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunSort(t *testing.T) {
+	withStdin(t, "529.982.247-25\n11144477735\n123\n")
+	code, stdout, _ := runCLI(t, "normalize", "--sort", "--format=plain")
+	if want := "111.444.777-35\tfalse\n123\tfalse\n529.982.247-25\tfalse\n"; code != 0 || stdout != want {
+		t.Errorf("run(normalize --sort) = %d, %q, want %q", code, stdout, want)
+	}
+
+	withStdin(t, "111.444.777-35\n529.982.247-25\n123\n")
+	code, stdout, _ = runCLI(t, "validate", "--sort=desc", "--format=plain")
+	if want := "529.982.247-25\ttrue\n123\tfalse\n111.444.777-35\ttrue\n"; code != 0 || stdout != want {
+		t.Errorf("run(validate --sort=desc) = %d, %q, want %q", code, stdout, want)
+	}
+
+	if code, _, _ := runCLI(t, "validate", "52998224725", "--sort=up"); code != 1 {
+		t.Errorf("run(validate --sort=up) = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateLargeCount(t *testing.T) {
+	const count = 200000
+	code, stdout, _ := runCLI(t, "generate", "--count=200000", "--allow-duplicates", "--unformatted", "--separator=,")
+	if code != 0 {
+		t.Fatalf("run(generate --count=%d) = %d", count, code)
+	}
+	if n := strings.Count(stdout, ","); n != count-1 {
+		t.Errorf("output has %d separators, want %d", n, count-1)
+	}
+	if len(stdout) != count*11+count-1 {
+		t.Errorf("output has %d bytes, want %d", len(stdout), count*11+count-1)
+	}
+
+	code, stdout, _ = runCLI(t, "generate", "--count=200000", "--allow-duplicates")
+	if code != 0 || strings.Count(stdout, "\n") != count || !strings.HasSuffix(stdout, "\n") {
+		t.Errorf("run(generate --count=%d) = %d with %d lines", count, code, strings.Count(stdout, "\n"))
+	}
+}
+
+func TestRunValidateGroupByRegion(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("529.982.247-25\n123.456.788-10\n123\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "validate", "--file="+filename, "--group-by-region")
+	if code != 0 {
+		t.Fatalf("run(validate --group-by-region) = %d (stderr %q)", code, stderr)
+	}
+	var got map[string][]string
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output is not a JSON object: %v\n%s", err, stdout)
+	}
+	want := map[string][]string{
+		"7":       {"529.982.247-25", "111.444.777-35"},
+		"8":       {"123.456.788-10"},
+		"invalid": {"123"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestRunGenerateRateConflicts(t *testing.T) {
+	for _, args := range [][]string{
+		{"generate", "--rate=0"},
+		{"generate", "--rate=10", "--count=5"},
+		{"generate", "--rate=10", "--json"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunValidateExpectRegion(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{[]string{"validate", "529.982.247-25", "--expect-region=7"}, 0, "valid\n"},
+		{[]string{"validate", "529.982.247-25", "--expect-region=8"}, 0, "invalid\n"},
+		{[]string{"validate", "529.982.247-25", "--expect-region=8", "--strict"}, 1, "invalid\n"},
+		{[]string{"validate", "529.982.247-25", "--expect-region=10"}, 1, ""},
+	}
+
+	for _, tt := range tests {
+		code, stdout, _ := runCLI(t, tt.args...)
+		if code != tt.wantCode || stdout != tt.wantOutput {
+			t.Errorf("run(%v) = %d, %q, want %d, %q", tt.args, code, stdout, tt.wantCode, tt.wantOutput)
+		}
+	}
+
+	code, stdout, _ := runCLI(t, "validate", "529.982.247-25", "--expect-region=8", "--json")
+	if results := decodeResults(t, stdout); code != 0 || len(results) != 1 || results[0].Reason != "region" {
+		t.Errorf("run(validate --expect-region --json) = %d, %q", code, stdout)
+	}
+}
+
+func TestRunGenerateChunkSize(t *testing.T) {
+	prefix := t.TempDir() + "/cpfs"
+	code, _, stderr := runCLI(t, "generate", "--count=25", "--chunk-size=10", "--output="+prefix)
+	if code != 0 {
+		t.Fatalf("run(generate --chunk-size) = %d (stderr %q)", code, stderr)
+	}
+
+	for i, want := range []int{10, 10, 5} {
+		name := fmt.Sprintf("%s-%04d.json", prefix, i+1)
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results := decodeResults(t, string(data)); len(results) != want {
+			t.Errorf("%s holds %d results, want %d", name, len(results), want)
+		}
+	}
+	if _, err := os.Stat(prefix + "-0004.json"); !os.IsNotExist(err) {
+		t.Errorf("unexpected fourth chunk: %v", err)
+	}
+
+	code, _, _ = runCLI(t, "generate", "--count=5", "--invalid-rate=0.5", "--chunk-size=2", "--format=jsonl", "--output="+prefix)
+	if _, err := os.Stat(prefix + "-0003.jsonl"); code != 0 || err != nil {
+		t.Errorf("run(generate --invalid-rate --chunk-size) = %d, third chunk: %v", code, err)
+	}
+
+	if code, _, _ := runCLI(t, "generate", "--count=5", "--chunk-size=2"); code != 1 {
+		t.Errorf("run(generate --chunk-size without --output) = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateVerify(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "generate", "--count=50", "--verify")
+	if code != 0 {
+		t.Fatalf("run(generate --verify) = %d (stderr %q)", code, stderr)
+	}
+	if got := strings.Count(stdout, "\n"); got != 50 {
+		t.Errorf("run(generate --verify) printed %d CPFs, want 50", got)
+	}
+
+	if code, _, _ := runCLI(t, "generate", "--invalid", "--verify"); code != 0 {
+		t.Errorf("run(generate --invalid --verify) = %d, want 0", code)
+	}
+	if code, _, _ := runCLI(t, "generate", "--invalid-rate=0.5", "--verify"); code != 1 {
+		t.Errorf("run(generate --invalid-rate --verify) = %d, want 1", code)
+	}
+
+	// A CPF with a broken DV, as a faulty algorithm would produce
+	broken := func(yield func(string, error) bool) {
+		for _, c := range []string{"529.982.247-25", "529.982.247-26", "111.444.777-35"} {
+			if !yield(c, nil) {
+				return
+			}
+		}
+	}
+	var got []string
+	var gotErr error
+	for c, err := range verifyStream(broken) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		got = append(got, c)
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "529.982.247-26") {
+		t.Errorf("verifyStream() error = %v, want one naming 529.982.247-26", gotErr)
+	}
+	if len(got) != 1 {
+		t.Errorf("verifyStream() yielded %v before failing, want only the first CPF", got)
+	}
+}
+
+func TestRunInputEncoding(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("529.982.247-25 Jos\xe9\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "validate", "--file="+filename, "--input-encoding=latin1", "--json")
+	if code != 0 {
+		t.Fatalf("run(validate --input-encoding=latin1) = %d (stderr %q)", code, stderr)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 1 || results[0].Original != "529.982.247-25 José" {
+		t.Errorf("results = %+v, want original %q", results, "529.982.247-25 José")
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--input-encoding=ebcdic"); code != 1 {
+		t.Errorf("run(validate --input-encoding=ebcdic) = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateSQL(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "generate", "--from=529982247", "--to=529982247", "--output-format=sql", "--table=users", "--column=cpf")
+	if code != 0 {
+		t.Fatalf("run(generate --output-format=sql) = %d (stderr %q)", code, stderr)
+	}
+	if want := "INSERT INTO users (cpf) VALUES ('529.982.247-25');\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	code, stdout, _ = runCLI(t, "generate", "--count=3", "--invalid-rate=0.5", "--format=sql", "--table=people", "--unformatted")
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if code != 0 || len(lines) != 3 || !strings.HasPrefix(lines[0], "INSERT INTO people (cpf) VALUES ('") {
+		t.Errorf("run(generate --invalid-rate --format=sql) = %d, %q", code, stdout)
+	}
+
+	for _, args := range [][]string{
+		{"generate", "--format=sql"},
+		{"generate", "--format=sql", "--table=users; --"},
+		{"generate", "--table=users"},
+		{"validate", "52998224725", "--format=sql"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunGenerateMinMaxCount(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		code, stdout, stderr := runCLI(t, "generate", "--min-count=2", "--max-count=6")
+		if code != 0 {
+			t.Fatalf("run(generate --min-count --max-count) = %d (stderr %q)", code, stderr)
+		}
+		if n := strings.Count(stdout, "\n"); n < 2 || n > 6 {
+			t.Fatalf("run(generate --min-count=2 --max-count=6) printed %d CPFs", n)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"generate", "--min-count=6", "--max-count=2"},
+		{"generate", "--min-count=0", "--max-count=2"},
+		{"generate", "--min-count=2"},
+		{"generate", "--min-count=2", "--max-count=6", "--count=3"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunTelemetryResetID(t *testing.T) {
+	code, events := runCLITracked(t, "validate", "52998224725")
+	if code != 0 || len(events) != 1 {
+		t.Fatalf("run(validate) = %d with %d events", code, len(events))
+	}
+	if id := events[0].DistinctID; len(id) != 36 || strings.Contains(id, events[0].OS) {
+		t.Errorf("event DistinctID = %q, want an anonymous UUID", id)
+	}
+
+	code, stdout, _ := runCLI(t, "telemetry", "reset-id")
+	if code != 0 || !strings.HasPrefix(stdout, "Telemetry ID reset to ") {
+		t.Errorf("run(telemetry reset-id) = %d, %q", code, stdout)
+	}
+}
+
+func TestRunParse(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "parse", "52998224725")
+	if code != 0 {
+		t.Fatalf("run(parse) = %d (stderr %q)", code, stderr)
+	}
+	var got cpf.ParsedCPF
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output is not JSON: %q", stdout)
+	}
+	want := cpf.ParsedCPF{Base: "529982247", CheckDigits: "25", Region: 7, Valid: true, Formatted: "529.982.247-25"}
+	if got != want {
+		t.Errorf("run(parse) = %+v, want %+v", got, want)
+	}
+
+	for _, args := range [][]string{{"parse", "5299822472"}, {"parse"}} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestRunValidateMultipleArgs(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "validate", "111.444.777-35", "52998224725", "12345")
+	if code != 0 {
+		t.Fatalf("run(validate a b c) = %d (stderr %q)", code, stderr)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3: %q", len(results), stdout)
+	}
+	for i, want := range []bool{true, true, false} {
+		if results[i].Valid != want {
+			t.Errorf("results[%d] = %+v, want valid %v", i, results[i], want)
+		}
+	}
+	if results[2].CPF != "12345" {
+		t.Errorf("results[2].CPF = %q, want %q", results[2].CPF, "12345")
+	}
+
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stdout, _ = runCLI(t, "validate", "--file="+filename, "111.444.777-35", "12345")
+	if results := decodeResults(t, stdout); len(results) != 1 || results[0].CPF != "52998224725" {
+		t.Errorf("run(validate --file a b) = %+v, want only the file's CPF", results)
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--cpf=52998224725", "111.444.777-35"); code != 1 {
+		t.Errorf("run(validate --cpf a) = %d, want 1", code)
+	}
+}
+
+func TestRunSkipHeader(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("cpf\n52998224725\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runCLI(t, "validate", "--file="+filename, "--skip-header", "--strict")
+	if code != 0 {
+		t.Fatalf("run(validate --skip-header --strict) = %d (stderr %q)", code, stderr)
+	}
+	results := decodeResults(t, stdout)
+	if len(results) != 2 || results[0].CPF != "52998224725" {
+		t.Errorf("results = %+v, want the two CPFs after the header", results)
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--file="+filename, "--strict"); code != 1 {
+		t.Errorf("run(validate --strict) with a header = %d, want 1", code)
+	}
+}
+
+func TestRunGenerateExcludeRegions(t *testing.T) {
+	code, stdout, stderr := runCLI(t, "generate", "--count=200", "--exclude-regions=3,7")
+	if code != 0 {
+		t.Fatalf("run(generate --exclude-regions) = %d (stderr %q)", code, stderr)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(stdout, "\n"), "\n") {
+		code, _, err := cpf.Region(line)
+		if err != nil || code == 3 || code == 7 || !cpf.ValidateCPF(line, false) {
+			t.Fatalf("generated %q from region %d (%v)", line, code, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"generate", "--exclude-regions=0,1,2,3,4,5,6,7,8,9"},
+		{"generate", "--exclude-regions=3,x"},
+		{"generate", "--exclude-regions=3", "--region=3"},
+		{"generate", "--exclude-regions=3", "--invalid"},
+	} {
+		if code, _, _ := runCLI(t, args...); code != 1 {
+			t.Errorf("run(%v) = %d, want 1", args, code)
+		}
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	filename := t.TempDir() + "/cpfs.txt"
+	if err := os.WriteFile(filename, []byte("52998224725\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	totals := make(chan int)
+	done := make(chan error)
+	go func() {
+		done <- watchFile(ctx, filename, 10*time.Millisecond, func() error {
+			results, err := cpf.ProcessFile(filename, cpf.ValidateProcessor)
+			if err != nil {
+				return err
+			}
+			totals <- len(results)
+			return nil
+		})
+	}()
+
+	waitTotal := func(want int) {
+		t.Helper()
+		select {
+		case got := <-totals:
+			if got != want {
+				t.Fatalf("processed %d CPFs, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("file was not processed again within 5s (want %d CPFs)", want)
+		}
+	}
+	waitTotal(1)
+
+	// Append a CPF, moving the modification time forward in case the file
+	// system's clock is too coarse to notice
+	if err := os.WriteFile(filename, []byte("52998224725\n111.444.777-35\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(2 * time.Second)
+	if err := os.Chtimes(filename, later, later); err != nil {
+		t.Fatal(err)
+	}
+	waitTotal(2)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("watchFile() error = %v, want %v", err, context.Canceled)
+	}
+
+	// A file vanishing while fn reads it is retried rather than fatal
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err := watchFile(ctx, filename, time.Millisecond, func() error {
+		calls++
+		switch calls {
+		case 1:
+			later := time.Now().Add(4 * time.Second)
+			return os.Chtimes(filename, later, later)
+		case 2:
+			return fmt.Errorf("failed to open file: %w", fs.ErrNotExist)
+		case 3:
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 3 {
+		t.Errorf("watchFile() = %v after %d calls, want %v after 3", err, calls, context.Canceled)
+	}
+
+	if code, _, _ := runCLI(t, "validate", "--watch", "--file="+filename, "--json"); code != 1 {
+		t.Errorf("run(validate --watch --json) = %d, want 1", code)
+	}
+	if code, _, _ := runCLI(t, "validate", "--watch", "52998224725"); code != 1 {
+		t.Errorf("run(validate --watch) without --file = %d, want 1", code)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runMask handles the "mask" command, printing one redacted CPF per line.
+func runMask(command string, args []string, stdout, stderr io.Writer) int {
+	opts := cpf.DefaultMaskOptions
+	input := ""
+	filename := ""
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--visible-start="), strings.HasPrefix(arg, "--visible-end="):
+			name, value, _ := strings.Cut(arg, "=")
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return failf(stderr, command, err, msg("invalid_mask_count"), name, value)
+			}
+			if name == "--visible-start" {
+				opts.VisibleStart = n
+			} else {
+				opts.VisibleEnd = n
+			}
+		case strings.HasPrefix(arg, "--mask-char="):
+			value := strings.TrimPrefix(arg, "--mask-char=")
+			if utf8.RuneCountInString(value) != 1 {
+				err := fmt.Errorf("invalid mask character '%s'", value)
+				return failf(stderr, command, err, msg("invalid_mask_char"), value)
+			}
+			opts.MaskChar, _ = utf8.DecodeRuneInString(value)
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			return failf(stderr, command, err, msg("unknown_option"), arg)
+		default:
+			input = arg
+		}
+	}
+
+	var inputs []string
+	switch {
+	case filename != "":
+		results, err := cpf.ProcessFile(filename, func(line string) cpf.CPFResult {
+			return cpf.CPFResult{CPF: line}
+		})
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		for _, r := range results {
+			inputs = append(inputs, r.CPF)
+		}
+	case input != "":
+		inputs = []string{input}
+	default:
+		err := fmt.Errorf("missing CPF to mask")
+		failf(stderr, command, err, "%s", msg("missing_mask"))
+		printUsage(stdout)
+		return 1
+	}
+
+	for _, in := range inputs {
+		masked, err := cpf.MaskCPF(in, opts)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		fmt.Fprintln(stdout, masked)
+	}
+	return 0
+}
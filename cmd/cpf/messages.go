@@ -0,0 +1,439 @@
+package main
+
+import (
+	"fmt"
+
+	"os"
+	"strings"
+)
+
+// messages holds the user-facing messages in each supported language, keyed
+// by language and then by message. Messages missing from a language fall back
+// to English.
+var messages = map[string]map[string]string{
+	"en": {
+		"help_header":          "CPF Tool\nDeveloped by Diego Peixoto for aquarela.io\nCopyleft © 2024-%d",
+		"error":                "Error: %v",
+		"unknown_option":       "Error: Unknown option '%s'",
+		"missing_validate":     "Error: Missing CPF to validate.",
+		"missing_format":       "Error: Missing CPF to format.",
+		"length_short":         "Error: invalid CPF number: got %d digits, need 11 (%d short)",
+		"length_long":          "Error: invalid CPF number: got %d digits, need 11 (%d too many)",
+		"cpf_conflict":         "Error: Give the CPF either as an argument or with --cpf, not both.",
+		"invalid_only":         "Error: Invalid only value '%s'. Must be valid or invalid.",
+		"invalid_input_format": "Error: Invalid input-format value '%s'. Must be lines or csv.",
+		"invalid_limit":        "Error: Invalid limit value '%s'. Must be a positive number.",
+		"invalid_workers":      "Error: Invalid workers value '%s'. Must be a positive number.",
+
+		// Commands and global flags
+		"unknown_command":    "Error: Unknown command '%s'",
+		"unsupported_lang":   "Error: Unsupported language '%s'. Must be pt or en.",
+		"invalid_format":     "invalid format '%s': must be one of %s",
+		"csv_requires_file":  "--input-format=csv requires --file",
+		"write_file_error":   "error writing to file: %v",
+		"marshal_json_error": "error marshaling JSON: %v",
+
+		// generate
+		"generate_error":          "Error generating CPFs: %v",
+		"invalid_region":          "Error: Invalid region value '%s'. Must be a number between 0 and 9.",
+		"invalid_seed":            "Error: Invalid seed value '%s'. Must be an integer.",
+		"invalid_invalid_rate":    "Error: Invalid invalid-rate value '%s'. Must be a number between 0 and 1.",
+		"invalid_prefix":          "Error: Invalid prefix value '%s': %v",
+		"region_invalid_conflict": "--region cannot be combined with --invalid",
+		"invalid_rate_conflict":   "--invalid-rate cannot be combined with --region or --invalid",
+		"prefix_conflict":         "--prefix cannot be combined with --region, --invalid or --invalid-rate",
+
+		// normalize, mask, dedupe, diff and extract
+		"missing_normalize":  "Error: Missing CPF to normalize.",
+		"missing_mask":       "Error: Missing CPF to mask.",
+		"invalid_mask_count": "Error: Invalid %s value '%s'. Must be a non-negative number.",
+		"invalid_mask_char":  "Error: Invalid --mask-char value '%s'. Must be a single character.",
+		"missing_dedupe":     "Error: Missing CPFs to dedupe. Use --file or pipe them through stdin.",
+		"missing_diff":       "Error: Missing files to compare. Use --old=FILE and --new=FILE.",
+		"missing_extract":    "Error: Missing text to extract CPFs from. Use --file or pipe it through stdin.",
+
+		// cnpj
+		"missing_cnpj_validate": "Error: Missing CNPJ to validate.",
+		"missing_cnpj_format":   "Error: Missing CNPJ to format.",
+		"invalid_count":         "Error: Invalid count value '%s'. Must be a positive number.",
+		"cnpj_generate_error":   "Error generating CNPJ: %v",
+
+		// completion, benchmark, serve and telemetry
+		"unsupported_shell":         "Error: Unsupported shell '%s'. Must be bash, zsh or fish.",
+		"invalid_duration":          "Error: Invalid duration value '%s'. Must be a positive duration such as 2s.",
+		"listening":                 "Listening on %s",
+		"telemetry_enable_error":    "Error enabling telemetry: %v",
+		"telemetry_disable_error":   "Error disabling telemetry: %v",
+		"telemetry_configure_error": "Error configuring telemetry: %v",
+
+		// verify
+		"missing_verify": "Error: Missing file to verify. Use --file=FILE.",
+
+		// validate --with-line-numbers
+		"line_numbers_conflict": "--with-line-numbers can't be combined with --input-format=csv or --workers",
+
+		// --append
+		"append_requires_output": "--append requires --output",
+		"append_format_conflict": "--append cannot be combined with --format=%s; use jsonl or plain",
+
+		// generate --pattern
+		"invalid_pattern":  "Error: Invalid pattern value '%s': %v",
+		"pattern_conflict": "--pattern cannot be combined with --prefix, --region, --invalid or --invalid-rate",
+
+		// reformat
+		"invalid_to":       "Error: Invalid to value '%s'. Must be formatted or digits.",
+		"missing_to":       "Error: Missing --to. Use --to=formatted or --to=digits.",
+		"missing_reformat": "Error: Missing CPFs to reformat. Use --file or pipe them through stdin.",
+		"invalid_line":     "line %d: invalid CPF '%s'",
+
+		// validate --fail-fast
+		"fail_fast_conflict": "--fail-fast requires --file and can't be combined with --input-format=csv or --workers",
+		"invalid_cpf_line":   "Error: Invalid CPF '%s' on line %d.",
+
+		// complete
+		"missing_complete": "Error: Missing digits to complete.",
+
+		// count
+		"missing_count": "Error: Missing CPFs to count. Use --file or pipe them through stdin.",
+
+		// validate with several files
+		"several_files_conflict": "several --file options can't be combined with --input-format=csv, --workers or --fail-fast",
+
+		// validate --max-errors
+		"invalid_max_errors":  "Error: Invalid max-errors value '%s'. Must be a positive number.",
+		"max_errors_conflict": "--max-errors can't be combined with --input-format=csv or --workers",
+		"max_errors":          "Error: Exceeded max errors: stopped after %d invalid CPFs.",
+
+		// --compact
+		"compact_conflict": "--compact cannot be combined with --format=%s",
+
+		// --template
+		"template_conflict":      "--template cannot be combined with --format or --compact",
+		"template_json_conflict": "--template cannot be combined with --format, --compact or --json",
+		"write_output_error":     "error writing output: %v",
+
+		// selftest
+		"selftest_failed": "Error: Self-test failed:\n%v",
+
+		// generate --from and --to
+		"invalid_from":     "Error: Invalid from value '%s'. Must be a number between 0 and 999999999.",
+		"invalid_to_base":  "Error: Invalid to value '%s'. Must be a number between 0 and 999999999.",
+		"from_to_together": "--from and --to must be given together",
+
+		// validate --output-dir
+		"mkdir_error": "error creating output directory: %v",
+
+		// --envelope
+		"json_object_conflict":     "--json-object cannot be combined with --json, --format, --compact, --envelope, --template, --append or --invalid-rate",
+		"envelope_conflict":        "--envelope cannot be combined with --compact, --append or --template",
+		"envelope_format_conflict": "--envelope cannot be combined with --format=%s",
+		"output_dir_conflict":      "--output-dir cannot be combined with --output, --append, --format, --json, --compact, --envelope, --template or --only",
+
+		// --sort
+		"invalid_sort": "Error: Invalid sort value '%s'. Must be asc or desc.",
+
+		// validate --group-by-region
+		"group_by_region_conflict": "--group-by-region cannot be combined with --output-dir, --append, --format, --compact, --envelope, --template or --only",
+
+		// generate --rate
+		"invalid_rate":  "Error: Invalid rate value '%s'. Must be a positive number.",
+		"rate_conflict": "--rate can only be combined with --invalid, --unformatted and --seed",
+
+		// validate --expect-region
+		"invalid_expect_region":  "Error: Invalid expect-region value '%s'. Must be a number between 0 and 9.",
+		"expect_region_conflict": "--expect-region can't be combined with --workers",
+
+		// generate --chunk-size
+		"invalid_chunk_size":         "Error: Invalid chunk-size value '%s'. Must be a positive number.",
+		"chunk_size_requires_output": "--chunk-size requires --output",
+		"chunk_size_conflict":        "--chunk-size cannot be combined with --append, --template, --json-object or --rate",
+
+		// hash
+		"missing_salt": "Error: Missing --salt. An unsalted hash of a CPF is easy to reverse.",
+		"missing_hash": "Error: Missing CPF to hash.",
+
+		// generate --verify
+		"verify_conflict":     "--verify cannot be combined with --rate or --invalid-rate",
+		"verification_failed": "verification failed: generated CPF %s is invalid",
+
+		// --input-encoding
+		"invalid_encoding": "invalid input encoding '%s': must be one of %s",
+
+		// generate --format=sql
+		"sql_requires_table":      "--format=sql requires --table",
+		"sql_chunk_size_conflict": "--chunk-size cannot be combined with --format=sql",
+		"table_requires_sql":      "--table and --column require --format=sql",
+
+		// compare
+		"compare_count": "Error: Give exactly two CPFs to compare.",
+
+		// generate --min-count and --max-count
+		"invalid_count_range": "Error: Invalid %s value '%s'. Must be a positive number.",
+		"min_max_together":    "--min-count and --max-count must be given together",
+		"min_max_conflict":    "--min-count and --max-count cannot be combined with --count, --from, --to or --rate",
+
+		// telemetry reset-id
+		"telemetry_reset_error": "Error resetting telemetry ID: %v",
+
+		// generate --count=0
+		"invalid_generate_count": "Error: Invalid count value '%s'. Must be a positive number, or 0 to generate until interrupted.",
+		"unbounded_conflict":     "--count=0 can only be combined with --invalid, --unformatted and --seed",
+
+		// parse
+		"missing_parse": "Error: Missing CPF to parse.",
+
+		// generate --exclude-regions
+		"invalid_exclude_regions":        "Error: Invalid exclude-regions value '%s'. Must be a comma-separated list of numbers between 0 and 9.",
+		"invalid_exclude_regions_reason": "Error: Invalid exclude-regions value '%s': %v",
+		"exclude_regions_conflict":       "--exclude-regions cannot be combined with --region, --invalid, --invalid-rate, --prefix or --pattern",
+		"from_to_conflict":               "--from and --to cannot be combined with --count, --pattern, --prefix, --region, --exclude-regions, --invalid or --invalid-rate",
+
+		// validate --watch
+		"watch_conflict":  "--watch requires a single --file and can only be combined with --delimiter, --input-encoding, --skip-header, --limit and --expect-region",
+		"open_file_error": "failed to open file: %v",
+	},
+	"pt": {
+		"help_header":          "Ferramenta de CPF\nDesenvolvida por Diego Peixoto para aquarela.io\nCopyleft © 2024-%d",
+		"error":                "Erro: %v",
+		"unknown_option":       "Erro: opção desconhecida '%s'",
+		"missing_validate":     "Erro: informe o CPF a ser validado.",
+		"missing_format":       "Erro: informe o CPF a ser formatado.",
+		"length_short":         "Erro: número de CPF inválido: tem %d dígitos, precisa de 11 (faltam %d)",
+		"length_long":          "Erro: número de CPF inválido: tem %d dígitos, precisa de 11 (%d a mais)",
+		"cpf_conflict":         "Erro: informe o CPF como argumento ou com --cpf, não ambos.",
+		"invalid_only":         "Erro: valor de only inválido '%s'. Use valid ou invalid.",
+		"invalid_input_format": "Erro: valor de input-format inválido '%s'. Use lines ou csv.",
+		"invalid_limit":        "Erro: valor de limit inválido '%s'. Use um número positivo.",
+		"invalid_workers":      "Erro: valor de workers inválido '%s'. Use um número positivo.",
+
+		// Commands and global flags
+		"unknown_command":    "Erro: comando desconhecido '%s'",
+		"unsupported_lang":   "Erro: idioma não suportado '%s'. Use pt ou en.",
+		"invalid_format":     "formato inválido '%s': use um destes: %s",
+		"csv_requires_file":  "--input-format=csv requer --file",
+		"write_file_error":   "erro ao gravar o arquivo: %v",
+		"marshal_json_error": "erro ao gerar o JSON: %v",
+
+		// generate
+		"generate_error":          "Erro ao gerar CPFs: %v",
+		"invalid_region":          "Erro: valor de region inválido '%s'. Use um número entre 0 e 9.",
+		"invalid_seed":            "Erro: valor de seed inválido '%s'. Use um número inteiro.",
+		"invalid_invalid_rate":    "Erro: valor de invalid-rate inválido '%s'. Use um número entre 0 e 1.",
+		"invalid_prefix":          "Erro: valor de prefix inválido '%s': %v",
+		"region_invalid_conflict": "--region não pode ser combinado com --invalid",
+		"invalid_rate_conflict":   "--invalid-rate não pode ser combinado com --region ou --invalid",
+		"prefix_conflict":         "--prefix não pode ser combinado com --region, --invalid ou --invalid-rate",
+
+		// normalize, mask, dedupe, diff and extract
+		"missing_normalize":  "Erro: informe o CPF a ser normalizado.",
+		"missing_mask":       "Erro: informe o CPF a ser mascarado.",
+		"invalid_mask_count": "Erro: valor de %s inválido '%s'. Use um número não negativo.",
+		"invalid_mask_char":  "Erro: valor de --mask-char inválido '%s'. Use um único caractere.",
+		"missing_dedupe":     "Erro: informe os CPFs a deduplicar. Use --file ou envie pela entrada padrão.",
+		"missing_diff":       "Erro: informe os arquivos a comparar. Use --old=ARQUIVO e --new=ARQUIVO.",
+		"missing_extract":    "Erro: informe o texto de onde extrair CPFs. Use --file ou envie pela entrada padrão.",
+
+		// cnpj
+		"missing_cnpj_validate": "Erro: informe o CNPJ a ser validado.",
+		"missing_cnpj_format":   "Erro: informe o CNPJ a ser formatado.",
+		"invalid_count":         "Erro: valor de count inválido '%s'. Use um número positivo.",
+		"cnpj_generate_error":   "Erro ao gerar o CNPJ: %v",
+
+		// completion, benchmark, serve and telemetry
+		"unsupported_shell":         "Erro: shell não suportado '%s'. Use bash, zsh ou fish.",
+		"invalid_duration":          "Erro: valor de duração inválido '%s'. Use uma duração positiva, como 2s.",
+		"listening":                 "Escutando em %s",
+		"telemetry_enable_error":    "Erro ao ativar a telemetria: %v",
+		"telemetry_disable_error":   "Erro ao desativar a telemetria: %v",
+		"telemetry_configure_error": "Erro ao configurar a telemetria: %v",
+
+		// verify
+		"missing_verify": "Erro: informe o arquivo a verificar. Use --file=ARQUIVO.",
+
+		// validate --with-line-numbers
+		"line_numbers_conflict": "--with-line-numbers não pode ser combinado com --input-format=csv ou --workers",
+
+		// --append
+		"append_requires_output": "--append requer --output",
+		"append_format_conflict": "--append não pode ser combinado com --format=%s; use jsonl ou plain",
+
+		// generate --pattern
+		"invalid_pattern":  "Erro: valor de pattern inválido '%s': %v",
+		"pattern_conflict": "--pattern não pode ser combinado com --prefix, --region, --invalid ou --invalid-rate",
+
+		// reformat
+		"invalid_to":       "Erro: valor de to inválido '%s'. Use formatted ou digits.",
+		"missing_to":       "Erro: informe --to. Use --to=formatted ou --to=digits.",
+		"missing_reformat": "Erro: informe os CPFs a reformatar. Use --file ou envie pela entrada padrão.",
+		"invalid_line":     "linha %d: CPF inválido '%s'",
+
+		// validate --fail-fast
+		"fail_fast_conflict": "--fail-fast requer --file e não pode ser combinado com --input-format=csv ou --workers",
+		"invalid_cpf_line":   "Erro: CPF inválido '%s' na linha %d.",
+
+		// complete
+		"missing_complete": "Erro: informe os dígitos a completar.",
+
+		// count
+		"missing_count": "Erro: informe os CPFs a contar. Use --file ou envie pela entrada padrão.",
+
+		// validate with several files
+		"several_files_conflict": "várias opções --file não podem ser combinadas com --input-format=csv, --workers ou --fail-fast",
+
+		// validate --max-errors
+		"invalid_max_errors":  "Erro: valor de max-errors inválido '%s'. Use um número positivo.",
+		"max_errors_conflict": "--max-errors não pode ser combinado com --input-format=csv ou --workers",
+		"max_errors":          "Erro: limite de erros excedido: parou após %d CPFs inválidos.",
+
+		// --compact
+		"compact_conflict": "--compact não pode ser combinado com --format=%s",
+
+		// --template
+		"template_conflict":      "--template não pode ser combinado com --format ou --compact",
+		"template_json_conflict": "--template não pode ser combinado com --format, --compact ou --json",
+		"write_output_error":     "erro ao escrever a saída: %v",
+
+		// selftest
+		"selftest_failed": "Erro: o autoteste falhou:\n%v",
+
+		// generate --from and --to
+		"invalid_from":     "Erro: valor de from inválido '%s'. Use um número entre 0 e 999999999.",
+		"invalid_to_base":  "Erro: valor de to inválido '%s'. Use um número entre 0 e 999999999.",
+		"from_to_together": "--from e --to devem ser informados juntos",
+
+		// validate --output-dir
+		"mkdir_error": "erro ao criar o diretório de saída: %v",
+
+		// --envelope
+		"json_object_conflict":     "--json-object não pode ser combinado com --json, --format, --compact, --envelope, --template, --append ou --invalid-rate",
+		"envelope_conflict":        "--envelope não pode ser combinado com --compact, --append ou --template",
+		"envelope_format_conflict": "--envelope não pode ser combinado com --format=%s",
+		"output_dir_conflict":      "--output-dir não pode ser combinado com --output, --append, --format, --json, --compact, --envelope, --template ou --only",
+
+		// --sort
+		"invalid_sort": "Erro: valor de sort inválido '%s'. Use asc ou desc.",
+
+		// validate --group-by-region
+		"group_by_region_conflict": "--group-by-region não pode ser combinado com --output-dir, --append, --format, --compact, --envelope, --template ou --only",
+
+		// generate --rate
+		"invalid_rate":  "Erro: valor de rate inválido '%s'. Use um número positivo.",
+		"rate_conflict": "--rate só pode ser combinado com --invalid, --unformatted e --seed",
+
+		// validate --expect-region
+		"invalid_expect_region":  "Erro: valor de expect-region inválido '%s'. Use um número entre 0 e 9.",
+		"expect_region_conflict": "--expect-region não pode ser combinado com --workers",
+
+		// generate --chunk-size
+		"invalid_chunk_size":         "Erro: valor de chunk-size inválido '%s'. Use um número positivo.",
+		"chunk_size_requires_output": "--chunk-size requer --output",
+		"chunk_size_conflict":        "--chunk-size não pode ser combinado com --append, --template, --json-object ou --rate",
+
+		// hash
+		"missing_salt": "Erro: informe --salt. Um hash de CPF sem salt é fácil de reverter.",
+		"missing_hash": "Erro: informe o CPF a ser convertido em hash.",
+
+		// generate --verify
+		"verify_conflict":     "--verify não pode ser combinado com --rate ou --invalid-rate",
+		"verification_failed": "falha na verificação: o CPF gerado %s é inválido",
+
+		// --input-encoding
+		"invalid_encoding": "codificação de entrada inválida '%s': use uma destas: %s",
+
+		// generate --format=sql
+		"sql_requires_table":      "--format=sql requer --table",
+		"sql_chunk_size_conflict": "--chunk-size não pode ser combinado com --format=sql",
+		"table_requires_sql":      "--table e --column requerem --format=sql",
+
+		// compare
+		"compare_count": "Erro: informe exatamente dois CPFs para comparar.",
+
+		// generate --min-count and --max-count
+		"invalid_count_range": "Erro: valor de %s inválido '%s'. Use um número positivo.",
+		"min_max_together":    "--min-count e --max-count devem ser informados juntos",
+		"min_max_conflict":    "--min-count e --max-count não podem ser combinados com --count, --from, --to ou --rate",
+
+		// telemetry reset-id
+		"telemetry_reset_error": "Erro ao redefinir o ID de telemetria: %v",
+
+		// generate --count=0
+		"invalid_generate_count": "Erro: valor de count inválido '%s'. Use um número positivo, ou 0 para gerar até ser interrompido.",
+		"unbounded_conflict":     "--count=0 só pode ser combinado com --invalid, --unformatted e --seed",
+
+		// parse
+		"missing_parse": "Erro: informe o CPF a ser analisado.",
+
+		// generate --exclude-regions
+		"invalid_exclude_regions":        "Erro: valor de exclude-regions inválido '%s'. Use uma lista de números entre 0 e 9 separados por vírgula.",
+		"invalid_exclude_regions_reason": "Erro: valor de exclude-regions inválido '%s': %v",
+		"exclude_regions_conflict":       "--exclude-regions não pode ser combinado com --region, --invalid, --invalid-rate, --prefix ou --pattern",
+		"from_to_conflict":               "--from e --to não podem ser combinados com --count, --pattern, --prefix, --region, --exclude-regions, --invalid ou --invalid-rate",
+
+		// validate --watch
+		"watch_conflict":  "--watch requer um único --file e só pode ser combinado com --delimiter, --input-encoding, --skip-header, --limit e --expect-region",
+		"open_file_error": "falha ao abrir o arquivo: %v",
+	},
+}
+
+// lang is the language of user-facing messages, set by --lang or LANG.
+var lang = "en"
+
+// msg returns the message for key in the current language.
+func msg(key string) string {
+	if m, ok := messages[lang][key]; ok {
+		return m
+	}
+	return messages["en"][key]
+}
+
+// msgError is an error whose message comes from the catalog. Error returns
+// the English message, which is what telemetry records, while fail reports it
+// in the current language.
+type msgError struct {
+	key  string
+	args []any
+}
+
+// msgErrorf returns an error with the message for key formatted with a. The
+// errors among a are wrapped, so errors.Is and errors.As see through it.
+func msgErrorf(key string, a ...any) error {
+	return &msgError{key: key, args: a}
+}
+
+func (e *msgError) Error() string {
+	return fmt.Sprintf(messages["en"][e.key], e.args...)
+}
+
+// Unwrap returns the errors among the message arguments.
+func (e *msgError) Unwrap() []error {
+	var errs []error
+	for _, a := range e.args {
+		if err, ok := a.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// localized returns the message in the current language.
+func (e *msgError) localized() string {
+	return fmt.Sprintf(msg(e.key), e.args...)
+}
+
+// localize returns the message of err, in the current language when it comes
+// from msgErrorf.
+func localize(err error) string {
+	if me, ok := err.(*msgError); ok {
+		return me.localized()
+	}
+	return err.Error()
+}
+
+// langFromEnv picks the message language from the LANG environment variable,
+// so that pt_BR.UTF-8 selects Portuguese.
+func langFromEnv() string {
+	if strings.HasPrefix(strings.ToLower(os.Getenv("LANG")), "pt") {
+		return "pt"
+	}
+	return "en"
+}
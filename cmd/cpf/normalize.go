@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runNormalize handles the "normalize" command.
+func runNormalize(command string, args []string, stdout, stderr io.Writer) int {
+	input := ""
+	filename := ""
+	outputFile := ""
+	appendMode := false
+	compact := false
+	envelope := false
+	sortOrder := ""
+	tmpl := ""
+	var opts cpf.ProcessOptions
+	format := ""
+	processor := cpf.NormalizeProcessor
+
+	for _, arg := range args {
+		switch {
+		case arg == "--unformatted":
+			processor = cpf.NormalizeDigitsProcessor
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case arg == "--append":
+			appendMode = true
+		case arg == "--compact":
+			compact = true
+		case arg == "--envelope":
+			envelope = true
+		case arg == "--sort":
+			sortOrder = "asc"
+		case strings.HasPrefix(arg, "--sort="):
+			sortOrder = strings.TrimPrefix(arg, "--sort=")
+			if sortOrder != "asc" && sortOrder != "desc" {
+				err := fmt.Errorf("invalid sort value '%s'", sortOrder)
+				return failf(stderr, command, err, msg("invalid_sort"), sortOrder)
+			}
+		case strings.HasPrefix(arg, "--template="):
+			tmpl = strings.TrimPrefix(arg, "--template=")
+			if err := parseTemplate(tmpl); err != nil {
+				return fail(stderr, command, err)
+			}
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "--format="):
+			f, err := parseFormat(strings.TrimPrefix(arg, "--format="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			format = f
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		default:
+			if input == "" {
+				input = arg
+			}
+		}
+	}
+	if tmpl != "" && (format != "" || compact) {
+		return fail(stderr, command, msgErrorf("template_conflict"))
+	}
+	if envelope {
+		f, err := envelopeFormat(format, compact, appendMode, tmpl)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if appendMode {
+		f, err := appendFormat(format, outputFile)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if compact {
+		f, err := compactFormat(format)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+
+	var results []cpf.CPFResult
+	var err error
+	switch {
+	case filename != "":
+		results, err = cpf.ProcessFileWithOptions(filename, opts, processor)
+	case input != "":
+		results = []cpf.CPFResult{processor(input)}
+	case stdinIsPiped():
+		results, err = cpf.ProcessReaderWithOptions(stdin, opts, processor)
+	default:
+		err := fmt.Errorf("missing CPF to normalize")
+		failf(stderr, command, err, "%s", msg("missing_normalize"))
+		printUsage(stdout)
+		return 1
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	if input == "" {
+		processed = len(results)
+	}
+
+	clearLineNumbers(results)
+	if sortOrder != "" {
+		cpf.SortResults(results, sortOrder == "desc")
+	}
+
+	if tmpl != "" {
+		err = writeTemplate(stdout, results, tmpl, outputFile, appendMode)
+	} else {
+		err = writeResults(stdout, results, outputFile, format, appendMode)
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	return 0
+}
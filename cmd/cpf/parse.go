@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runParse handles the "parse" command, printing the components of a CPF as
+// JSON.
+func runParse(command string, args []string, stdout, stderr io.Writer) int {
+	const usage = "Usage: cpf parse <cpf>"
+	input := ""
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsageLine(stdout, usage)
+			return 1
+		default:
+			if input == "" {
+				input = arg
+			}
+		}
+	}
+
+	if input == "" {
+		err := fmt.Errorf("missing CPF to parse")
+		failf(stderr, command, err, "%s", msg("missing_parse"))
+		printUsageLine(stdout, usage)
+		return 1
+	}
+
+	parsed, err := cpf.ParseCPF(input)
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	output, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return fail(stderr, command, msgErrorf("marshal_json_error", err))
+	}
+	fmt.Fprintln(stdout, string(output))
+	return 0
+}
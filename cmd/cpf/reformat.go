@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runReformat handles the "reformat" command. It converts every valid CPF to
+// the representation chosen with --to and reports invalid lines on stderr,
+// exiting 1 if there were any.
+func runReformat(command string, args []string, stdout, stderr io.Writer) int {
+	filename := ""
+	outputFile := ""
+	to := ""
+	var opts cpf.ProcessOptions
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--to="):
+			to = strings.TrimPrefix(arg, "--to=")
+			if to != "formatted" && to != "digits" {
+				err := fmt.Errorf("invalid to value '%s'", to)
+				return failf(stderr, command, err, msg("invalid_to"), to)
+			}
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+
+	if to == "" {
+		err := fmt.Errorf("missing --to")
+		failf(stderr, command, err, "%s", msg("missing_to"))
+		printUsageLine(stdout, "Usage: cpf reformat --to=formatted|digits [--file=FILE] [--output=FILE]")
+		return 1
+	}
+	render := cpf.FormatCPF
+	if to == "digits" {
+		render = cpf.ToDigits
+	}
+
+	var results []cpf.CPFResult
+	var err error
+	switch {
+	case filename != "":
+		results, err = cpf.ProcessFileWithOptions(filename, opts, cpf.ValidateProcessor)
+	case stdinIsPiped():
+		results, err = cpf.ProcessReaderWithOptions(stdin, opts, cpf.ValidateProcessor)
+	default:
+		err := fmt.Errorf("missing CPFs to reformat")
+		failf(stderr, command, err, "%s", msg("missing_reformat"))
+		printUsage(stdout)
+		return 1
+	}
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	processed = len(results)
+
+	var out strings.Builder
+	invalid := 0
+	for _, r := range results {
+		rendered, err := render(r.CPF)
+		if !r.Valid || err != nil {
+			fmt.Fprintf(stderr, msg("invalid_line")+"\n", r.Line, r.CPF)
+			invalid++
+			continue
+		}
+		out.WriteString(rendered + "\n")
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(out.String()), 0644); err != nil {
+			return fail(stderr, command, msgErrorf("write_file_error", err))
+		}
+	} else {
+		fmt.Fprint(stdout, out.String())
+	}
+
+	if invalid > 0 {
+		telemetry.Track(command, false, fmt.Errorf("%d invalid CPF(s)", invalid), nil)
+		return 1
+	}
+	return 0
+}
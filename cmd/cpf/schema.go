@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// resultSchema is the JSON Schema of the JSON output of validate, format,
+// normalize and generate: an array of results. Each line of jsonl output is
+// a single result, described by $defs/CPFResult. Keep it in sync with
+// cpf.CPFResult.
+var resultSchema = map[string]any{
+	"$schema":     "https://json-schema.org/draft/2020-12/schema",
+	"title":       "CPF results",
+	"description": "Results of a cpf validate, format, normalize or generate command.",
+	"type":        "array",
+	"items":       map[string]any{"$ref": "#/$defs/CPFResult"},
+	"$defs": map[string]any{
+		"CPFResult": map[string]any{
+			"type":                 "object",
+			"required":             []string{"cpf"},
+			"additionalProperties": false,
+			"properties": map[string]any{
+				"cpf": map[string]any{
+					"type":        "string",
+					"description": "The CPF, formatted or normalized by the command.",
+				},
+				"valid": map[string]any{
+					"type":        "boolean",
+					"description": "Whether the CPF is valid. Omitted when false, except for generated CPFs.",
+				},
+				"error": map[string]any{
+					"type":        "string",
+					"description": "Why the CPF could not be formatted or normalized, or how far a CPF of the wrong length is from 11 digits.",
+				},
+				"reason": map[string]any{
+					"type":        "string",
+					"enum":        []string{"length", "repeated", "check_digit", "non_numeric", "blocklisted", "region"},
+					"description": "Why the CPF is invalid.",
+				},
+				"original": map[string]any{
+					"type":        "string",
+					"description": "The CPF as it appeared in the input.",
+				},
+				"generated": map[string]any{
+					"type":        "boolean",
+					"description": "Set on CPFs produced by generate.",
+				},
+				"fields": map[string]any{
+					"type":                 "object",
+					"additionalProperties": map[string]any{"type": "string"},
+					"description":          "The other columns of the CSV row the CPF was read from.",
+				},
+				"line": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "The input line the CPF was read from, with --with-line-numbers or --fail-fast.",
+				},
+				"source": map[string]any{
+					"type":        "string",
+					"description": "The file the CPF was read from, when validating several files.",
+				},
+			},
+		},
+	},
+}
+
+// runSchema handles the "schema" command.
+func runSchema(command string, args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		err := fmt.Errorf("unknown option '%s'", args[0])
+		failf(stderr, command, err, msg("unknown_option"), args[0])
+		printUsageLine(stdout, "Usage: cpf schema")
+		return 1
+	}
+
+	output, err := json.MarshalIndent(resultSchema, "", "  ")
+	if err != nil {
+		return fail(stderr, command, msgErrorf("marshal_json_error", err))
+	}
+	fmt.Fprintln(stdout, string(output))
+	return 0
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// runSelfTest handles the "selftest" command, which checks the CPF algorithm
+// against known vectors and exits 1 if any of them fails.
+func runSelfTest(command string, args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		err := fmt.Errorf("unknown option '%s'", args[0])
+		failf(stderr, command, err, msg("unknown_option"), args[0])
+		printUsageLine(stdout, "Usage: cpf selftest")
+		return 1
+	}
+
+	if err := cpf.SelfTest(); err != nil {
+		return failf(stderr, command, err, msg("selftest_failed"), err)
+	}
+	fmt.Fprintln(stdout, "ok")
+	return 0
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/server"
+)
+
+// runServe handles the "serve" command, starting the HTTP server.
+func runServe(command string, args []string, stdout, stderr io.Writer) int {
+	addr := ":8080"
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--addr=") {
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+		addr = strings.TrimPrefix(arg, "--addr=")
+	}
+
+	srv := server.NewServer(addr)
+	fmt.Fprintf(stderr, msg("listening")+"\n", addr)
+	if err := srv.ListenAndServe(); err != nil {
+		return fail(stderr, command, err)
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runTelemetry handles the "telemetry" command and its subcommands.
+func runTelemetry(args []string, stdout, stderr io.Writer) int {
+	const usage = "Usage: cpf telemetry [enable|disable|status|configure|preview|reset-id]"
+	if len(args) < 1 {
+		printUsageLine(stdout, usage)
+		return 1
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "enable":
+		if err := telemetry.SetEnabled(true); err != nil {
+			fmt.Fprintf(stderr, msg("telemetry_enable_error")+"\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "Telemetry enabled")
+	case "disable":
+		if err := telemetry.SetEnabled(false); err != nil {
+			fmt.Fprintf(stderr, msg("telemetry_disable_error")+"\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "Telemetry disabled")
+	case "status":
+		if telemetry.IsEnabled() {
+			fmt.Fprintln(stdout, "Telemetry is enabled")
+		} else if telemetry.Suppressed() {
+			fmt.Fprintln(stdout, "Telemetry is disabled for this run (--no-telemetry)")
+		} else if telemetry.DisabledByEnv() {
+			fmt.Fprintln(stdout, "Telemetry is disabled by the environment (CPF_CLI_TELEMETRY or DO_NOT_TRACK)")
+		} else {
+			fmt.Fprintln(stdout, "Telemetry is disabled")
+		}
+	case "reset-id":
+		id, err := telemetry.ResetID()
+		if err != nil {
+			fmt.Fprintf(stderr, msg("telemetry_reset_error")+"\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Telemetry ID reset to %s\n", id)
+	case "configure":
+		return runTelemetryConfigure(args[1:], stdout, stderr)
+	default:
+		printUsageLine(stdout, usage)
+		return 1
+	}
+	return 0
+}
+
+// runTelemetryConfigure handles "telemetry configure", which points telemetry
+// at a self-hosted PostHog instance.
+func runTelemetryConfigure(args []string, stdout, stderr io.Writer) int {
+	const usage = "Usage: cpf telemetry configure [--endpoint=URL] [--api-key=KEY]"
+	if len(args) == 0 {
+		printUsageLine(stdout, usage)
+		return 1
+	}
+
+	for _, arg := range args {
+		var err error
+		switch {
+		case strings.HasPrefix(arg, "--endpoint="):
+			err = telemetry.SetEndpoint(strings.TrimPrefix(arg, "--endpoint="))
+		case strings.HasPrefix(arg, "--api-key="):
+			err = telemetry.SetAPIKey(strings.TrimPrefix(arg, "--api-key="))
+		default:
+			fmt.Fprintf(stderr, msg("unknown_option")+"\n", arg)
+			printUsageLine(stdout, usage)
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, msg("telemetry_configure_error")+"\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stdout, "Telemetry configured (endpoint: %s)\n", telemetry.Endpoint())
+	return 0
+}
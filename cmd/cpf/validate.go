@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runValidate handles the "validate" command.
+func runValidate(command string, args []string, stdout, stderr io.Writer) int {
+	var inputs []string
+	cpfFlag := ""
+	filename := ""
+	var filenames []string
+	outputFile := ""
+	outputDir := ""
+	groupByRegion := false
+	expectRegion := -1
+	appendMode := false
+	compact := false
+	envelope := false
+	sortOrder := ""
+	tmpl := ""
+	format := ""
+	useJSON := false
+	workers := 1
+	strict := false
+	only := ""
+	summary := false
+	summaryOnly := false
+	withLineNumbers := false
+	failFast := false
+	watch := false
+	maxErrors := 0
+	var opts cpf.ProcessOptions
+	inputFormat := "lines"
+	column := "cpf"
+
+	for _, arg := range args {
+		switch {
+		case arg == "--strict":
+			strict = true
+		case arg == "--json":
+			useJSON = true
+		case arg == "--summary":
+			summary = true
+		case arg == "--summary-only":
+			summaryOnly = true
+		case arg == "--with-line-numbers":
+			withLineNumbers = true
+		case arg == "--fail-fast":
+			failFast = true
+		case arg == "--watch":
+			watch = true
+		case arg == "--group-by-region":
+			groupByRegion = true
+		case strings.HasPrefix(arg, "--only="):
+			only = strings.TrimPrefix(arg, "--only=")
+			if only != "valid" && only != "invalid" {
+				err := fmt.Errorf("invalid only value '%s'", only)
+				return failf(stderr, command, err, msg("invalid_only"), only)
+			}
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		case strings.HasPrefix(arg, "--input-format="):
+			inputFormat = strings.TrimPrefix(arg, "--input-format=")
+			if inputFormat != "lines" && inputFormat != "csv" {
+				err := fmt.Errorf("invalid input format '%s'", inputFormat)
+				return failf(stderr, command, err, msg("invalid_input_format"), inputFormat)
+			}
+		case strings.HasPrefix(arg, "--column="):
+			column = strings.TrimPrefix(arg, "--column=")
+		case strings.HasPrefix(arg, "--cpf="):
+			cpfFlag = strings.TrimPrefix(arg, "--cpf=")
+		case strings.HasPrefix(arg, "--file="):
+			filenames = append(filenames, strings.TrimPrefix(arg, "--file="))
+		case arg == "--append":
+			appendMode = true
+		case arg == "--compact":
+			compact = true
+		case arg == "--envelope":
+			envelope = true
+		case arg == "--sort":
+			sortOrder = "asc"
+		case strings.HasPrefix(arg, "--sort="):
+			sortOrder = strings.TrimPrefix(arg, "--sort=")
+			if sortOrder != "asc" && sortOrder != "desc" {
+				err := fmt.Errorf("invalid sort value '%s'", sortOrder)
+				return failf(stderr, command, err, msg("invalid_sort"), sortOrder)
+			}
+		case strings.HasPrefix(arg, "--template="):
+			tmpl = strings.TrimPrefix(arg, "--template=")
+			if err := parseTemplate(tmpl); err != nil {
+				return fail(stderr, command, err)
+			}
+		case strings.HasPrefix(arg, "--output-dir="):
+			outputDir = strings.TrimPrefix(arg, "--output-dir=")
+		case strings.HasPrefix(arg, "--output="):
+			outputFile = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "--format="):
+			f, err := parseFormat(strings.TrimPrefix(arg, "--format="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			format = f
+		case strings.HasPrefix(arg, "--limit="):
+			limitStr := strings.TrimPrefix(arg, "--limit=")
+			n, err := strconv.Atoi(limitStr)
+			if err != nil || n <= 0 {
+				return failf(stderr, command, err, msg("invalid_limit"), limitStr)
+			}
+			opts.Limit = n
+		case strings.HasPrefix(arg, "--max-errors="):
+			maxStr := strings.TrimPrefix(arg, "--max-errors=")
+			n, err := strconv.Atoi(maxStr)
+			if err != nil || n <= 0 {
+				return failf(stderr, command, err, msg("invalid_max_errors"), maxStr)
+			}
+			maxErrors = n
+			opts.MaxErrors = n
+		case strings.HasPrefix(arg, "--expect-region="):
+			regionStr := strings.TrimPrefix(arg, "--expect-region=")
+			n, err := strconv.Atoi(regionStr)
+			if err != nil || n < 0 || n > 9 {
+				return failf(stderr, command, err, msg("invalid_expect_region"), regionStr)
+			}
+			expectRegion = n
+		case strings.HasPrefix(arg, "--workers="):
+			workersStr := strings.TrimPrefix(arg, "--workers=")
+			n, err := strconv.Atoi(workersStr)
+			if err != nil || n <= 0 {
+				return failf(stderr, command, err, msg("invalid_workers"), workersStr)
+			}
+			workers = n
+		case strings.HasPrefix(arg, "--"):
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		default:
+			inputs = append(inputs, arg)
+		}
+	}
+	if tmpl != "" && (format != "" || compact || useJSON) {
+		return fail(stderr, command, msgErrorf("template_json_conflict"))
+	}
+	if outputDir != "" && (outputFile != "" || appendMode || format != "" || useJSON || compact || envelope || tmpl != "" || only != "") {
+		return fail(stderr, command, msgErrorf("output_dir_conflict"))
+	}
+	if groupByRegion && (outputDir != "" || appendMode || format != "" || compact || envelope || tmpl != "" || only != "") {
+		return fail(stderr, command, msgErrorf("group_by_region_conflict"))
+	}
+	if envelope {
+		f, err := envelopeFormat(format, compact, appendMode, tmpl)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if appendMode {
+		f, err := appendFormat(format, outputFile)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if compact {
+		f, err := compactFormat(format)
+		if err != nil {
+			return fail(stderr, command, err)
+		}
+		format = f
+	}
+	if cpfFlag != "" {
+		if len(inputs) > 0 {
+			err := fmt.Errorf("CPF given both as an argument and with --cpf")
+			return failf(stderr, command, err, "%s", msg("cpf_conflict"))
+		}
+		inputs = []string{cpfFlag}
+	}
+
+	writeOutput := func(results []cpf.CPFResult) error {
+		if tmpl != "" {
+			return writeTemplate(stdout, results, tmpl, outputFile, appendMode)
+		}
+		return writeResults(stdout, results, outputFile, format, appendMode)
+	}
+
+	var results []cpf.CPFResult
+	var err error
+	plain := false
+	if len(filenames) > 0 {
+		filename = filenames[0]
+	}
+	if inputFormat == "csv" && filename == "" {
+		return fail(stderr, command, msgErrorf("csv_requires_file"))
+	}
+	if len(filenames) > 1 && (inputFormat == "csv" || workers > 1 || failFast) {
+		return fail(stderr, command, msgErrorf("several_files_conflict"))
+	}
+	if failFast && (filename == "" || inputFormat == "csv" || workers > 1) {
+		return fail(stderr, command, msgErrorf("fail_fast_conflict"))
+	}
+	if maxErrors > 0 && (inputFormat == "csv" || workers > 1) {
+		return fail(stderr, command, msgErrorf("max_errors_conflict"))
+	}
+	if expectRegion >= 0 && workers > 1 {
+		return fail(stderr, command, msgErrorf("expect_region_conflict"))
+	}
+	if withLineNumbers && (inputFormat == "csv" || workers > 1) {
+		return fail(stderr, command, msgErrorf("line_numbers_conflict"))
+	}
+
+	processor := cpf.ValidateProcessor
+	if expectRegion >= 0 {
+		processor = cpf.RegionProcessor(expectRegion)
+	}
+
+	// With --watch, the file is validated again each time it changes, with a
+	// fresh summary printed every time, until interrupted
+	if watch {
+		if len(filenames) != 1 || len(inputs) > 0 || inputFormat == "csv" || outputFile != "" || outputDir != "" ||
+			appendMode || format != "" || useJSON || compact || envelope || tmpl != "" || sortOrder != "" || only != "" ||
+			summary || summaryOnly || groupByRegion || withLineNumbers || strict || failFast || maxErrors > 0 || workers > 1 {
+			return fail(stderr, command, msgErrorf("watch_conflict"))
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		err := watchFile(ctx, filename, watchInterval, func() error {
+			results, err := cpf.ProcessFileWithOptions(filename, opts, processor)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "[%s] %s\n", time.Now().Format(time.TimeOnly), cpf.Summarize(results))
+			return nil
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return fail(stderr, command, err)
+		}
+		return 0
+	}
+
+	switch {
+	case len(filenames) > 1:
+		results, err = cpf.ProcessFilesWithOptions(filenames, opts, processor)
+	case inputFormat == "csv":
+		results, err = cpf.ProcessCSV(filename, column, opts, processor)
+	case failFast:
+		results, err = cpf.ProcessFileUntilInvalid(filename, opts, processor)
+	case filename != "" && workers > 1:
+		var lines []string
+		lines, err = cpf.ReadLines(filename, opts)
+		results = cpf.ValidateAll(lines, workers)
+	case filename != "":
+		results, err = cpf.ProcessFileWithOptions(filename, opts, processor)
+	case len(inputs) > 0:
+		for _, input := range inputs {
+			results = append(results, processor(input))
+		}
+		// Single CPF validation prints a plain line unless structured output
+		// was requested
+		plain = len(inputs) == 1 && !useJSON && format == "" && outputFile == "" && tmpl == "" && !groupByRegion
+	case stdinIsPiped():
+		// Validate CPFs piped through stdin
+		results, err = cpf.ProcessReaderWithOptions(stdin, opts, processor)
+	default:
+		err := fmt.Errorf("missing CPF to validate")
+		failf(stderr, command, err, "%s", msg("missing_validate"))
+		printUsage(stdout)
+		return 1
+	}
+	// Past --max-errors, the results read so far are still reported
+	tooManyErrors := errors.Is(err, cpf.ErrMaxErrors)
+	if err != nil && !tooManyErrors {
+		return fail(stderr, command, err)
+	}
+	if len(inputs) == 0 {
+		processed = len(results)
+	}
+
+	// With --fail-fast, reading stopped at the first invalid CPF, which is
+	// the only one reported
+	if failFast && len(results) > 0 && !results[len(results)-1].Valid {
+		first := results[len(results)-1]
+		if err := writeOutput([]cpf.CPFResult{first}); err != nil {
+			return fail(stderr, command, err)
+		}
+		err := fmt.Errorf("invalid CPF '%s' on line %d", first.CPF, first.Line)
+		return failf(stderr, command, err, msg("invalid_cpf_line"), first.CPF, first.Line)
+	}
+	if !withLineNumbers {
+		clearLineNumbers(results)
+	}
+
+	// Strict mode looks at every validated CPF, not just the ones kept by --only
+	allValid := cpf.AllValid(results)
+	counts := cpf.Summarize(results)
+	if only != "" {
+		results = cpf.FilterResults(results, only == "valid")
+	}
+	if sortOrder != "" {
+		cpf.SortResults(results, sortOrder == "desc")
+	}
+
+	switch {
+	case outputDir != "":
+		if err := writePartition(outputDir, results); err != nil {
+			return fail(stderr, command, err)
+		}
+	case summaryOnly:
+	case groupByRegion:
+		if err := writeRegionGroups(stdout, results, outputFile); err != nil {
+			return fail(stderr, command, err)
+		}
+	case plain:
+		for _, r := range results {
+			if r.Valid {
+				fmt.Fprintln(stdout, "valid")
+			} else {
+				fmt.Fprintln(stdout, "invalid")
+			}
+		}
+	default:
+		if err := writeOutput(results); err != nil {
+			return fail(stderr, command, err)
+		}
+	}
+	if summary || summaryOnly {
+		fmt.Fprintln(stderr, counts)
+	}
+
+	if tooManyErrors {
+		return failf(stderr, command, cpf.ErrMaxErrors, msg("max_errors"), maxErrors)
+	}
+
+	// In strict mode, any invalid CPF makes the command fail
+	if strict && !allValid {
+		telemetry.Track(command, false, fmt.Errorf("invalid CPF found"), nil)
+		return 1
+	}
+	return 0
+}
+
+// writePartition writes the CPFs of results to valid.txt, invalid.txt and
+// malformed.txt in dir, one per line, creating dir if needed.
+func writePartition(dir string, results []cpf.CPFResult) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return msgErrorf("mkdir_error", err)
+	}
+
+	valid, invalid, malformed := cpf.PartitionResults(results)
+	files := []struct {
+		name    string
+		results []cpf.CPFResult
+	}{
+		{"valid.txt", valid},
+		{"invalid.txt", invalid},
+		{"malformed.txt", malformed},
+	}
+	for _, f := range files {
+		var b strings.Builder
+		for _, r := range f.results {
+			b.WriteString(r.CPF)
+			b.WriteString("\n")
+		}
+		if err := os.WriteFile(filepath.Join(dir, f.name), []byte(b.String()), 0644); err != nil {
+			return msgErrorf("write_file_error", err)
+		}
+	}
+	return nil
+}
+
+// writeRegionGroups writes the CPFs of results as a JSON object grouping them
+// by fiscal region to outputFile, or to stdout when no output file is given.
+func writeRegionGroups(stdout io.Writer, results []cpf.CPFResult, outputFile string) error {
+	output, err := json.MarshalIndent(cpf.GroupByRegion(results), "", "  ")
+	if err != nil {
+		return msgErrorf("marshal_json_error", err)
+	}
+	output = append(output, '\n')
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, output, 0644); err != nil {
+			return msgErrorf("write_file_error", err)
+		}
+		return nil
+	}
+	if _, err := stdout.Write(output); err != nil {
+		return msgErrorf("write_output_error", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+	"github.com/diegopeixoto/cpf-cli-go/pkg/telemetry"
+)
+
+// runVerify handles the "verify" command. It prints nothing and exits 0 when
+// every CPF in the file is valid, and otherwise prints the line number of
+// each invalid CPF and exits 1.
+func runVerify(command string, args []string, stdout, stderr io.Writer) int {
+	filename := ""
+	var opts cpf.ProcessOptions
+
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--file="):
+			filename = strings.TrimPrefix(arg, "--file=")
+		case strings.HasPrefix(arg, "--delimiter="):
+			opts.Delimiter = strings.TrimPrefix(arg, "--delimiter=")
+		case arg == "--skip-header":
+			opts.SkipHeader = true
+		case strings.HasPrefix(arg, "--input-encoding="):
+			enc, err := parseEncoding(strings.TrimPrefix(arg, "--input-encoding="))
+			if err != nil {
+				return fail(stderr, command, err)
+			}
+			opts.Encoding = enc
+		default:
+			err := fmt.Errorf("unknown option '%s'", arg)
+			failf(stderr, command, err, msg("unknown_option"), arg)
+			printUsage(stdout)
+			return 1
+		}
+	}
+
+	if filename == "" {
+		err := fmt.Errorf("missing file to verify")
+		failf(stderr, command, err, "%s", msg("missing_verify"))
+		printUsageLine(stdout, "Usage: cpf verify --file=FILE")
+		return 1
+	}
+
+	results, err := cpf.ProcessFileWithOptions(filename, opts, cpf.ValidateProcessor)
+	if err != nil {
+		return fail(stderr, command, err)
+	}
+	processed = len(results)
+
+	// Report the line numbers of the file, counting blank lines, so they
+	// match what an editor shows
+	invalid := 0
+	for _, r := range results {
+		if !r.Valid {
+			fmt.Fprintln(stdout, r.Line)
+			invalid++
+		}
+	}
+	if invalid == 0 {
+		return 0
+	}
+	err = fmt.Errorf("%d invalid CPF(s)", invalid)
+	telemetry.Track(command, false, err, nil)
+	return 1
+}
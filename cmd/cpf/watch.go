@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// watchInterval is how often validate --watch checks its file for changes.
+const watchInterval = 500 * time.Millisecond
+
+// watchFile calls fn once, then again whenever the modification time or size
+// of filename changes, polling every interval until ctx is done, when it
+// returns ctx's error. A file that briefly disappears, as when an editor
+// saves by renaming a new file over it, is waited for, even if fn fails
+// because the file vanished while reading it. Any other error from fn ends
+// the watch.
+func watchFile(ctx context.Context, filename string, interval time.Duration, fn func() error) error {
+	last, err := os.Stat(filename)
+	if err != nil {
+		return msgErrorf("open_file_error", err)
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(filename)
+		if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+			continue
+		}
+		last = info
+		if err := fn(); errors.Is(err, fs.ErrNotExist) {
+			// Try again once the file is back
+			last = nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
@@ -2,6 +2,9 @@ module github.com/diegopeixoto/cpf-cli-go
 
 go 1.23.4
 
-require github.com/posthog/posthog-go v0.0.0-20240115103626-fbd687c18571
+require (
+	github.com/posthog/posthog-go v0.0.0-20240115103626-fbd687c18571
+	golang.org/x/text v0.21.0
+)
 
-require github.com/google/uuid v1.3.0 // indirect
+require github.com/google/uuid v1.3.0
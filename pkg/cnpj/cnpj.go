@@ -0,0 +1,135 @@
+package cnpj
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+var (
+	// firstWeights are applied to the first 12 digits to compute the first DV.
+	firstWeights = []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	// secondWeights are applied to the first 13 digits to compute the second DV.
+	secondWeights = []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+)
+
+func cryptoRandInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
+
+// UnformatCNPJ removes all non-digit characters from the input string.
+func UnformatCNPJ(cnpjStr string) string {
+	digits := make([]byte, 0, len(cnpjStr))
+	for i := 0; i < len(cnpjStr); i++ {
+		if c := cnpjStr[i]; c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	return string(digits)
+}
+
+// isRepeated checks if the string is composed entirely of the same character.
+func isRepeated(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s)
+}
+
+// FormatCNPJ formats a 14-digit CNPJ string as ##.###.###/####-##.
+func FormatCNPJ(cnpjStr string) (string, error) {
+	digits := UnformatCNPJ(cnpjStr)
+	if len(digits) != 14 {
+		return "", fmt.Errorf("invalid CNPJ number (must have 14 digits)")
+	}
+	return fmt.Sprintf("%s.%s.%s/%s-%s",
+		digits[0:2],
+		digits[2:5],
+		digits[5:8],
+		digits[8:12],
+		digits[12:14],
+	), nil
+}
+
+// getCD computes the 2 check digits (DV) from the first 12 digits of a CNPJ.
+func getCD(digits12 []int) ([2]int, error) {
+	if len(digits12) != 12 {
+		return [2]int{}, fmt.Errorf("invalid digits length: expected 12, got %d", len(digits12))
+	}
+
+	cd1 := cpf.Mod11CheckDigit(digits12, firstWeights)
+	cd2 := cpf.Mod11CheckDigit(append(append([]int{}, digits12...), cd1), secondWeights)
+
+	return [2]int{cd1, cd2}, nil
+}
+
+// ValidateCNPJ checks if the provided CNPJ string is valid.
+func ValidateCNPJ(cnpjStr string, byLength bool) bool {
+	unformatted := UnformatCNPJ(cnpjStr)
+	if len(unformatted) != 14 {
+		return false
+	}
+	if isRepeated(unformatted) {
+		return false
+	}
+
+	if byLength {
+		return true
+	}
+
+	digits12 := make([]int, 0, 12)
+	for _, ch := range unformatted[:12] {
+		digits12 = append(digits12, int(ch-'0'))
+	}
+
+	cd, err := getCD(digits12)
+	if err != nil {
+		return false
+	}
+	return unformatted[12:] == fmt.Sprintf("%d%d", cd[0], cd[1])
+}
+
+// GenerateCNPJ creates a random CNPJ number. The branch number (digits 9 to
+// 12) is always 0001, as it is for a company's headquarters.
+func GenerateCNPJ(formatted, invalid bool) (string, error) {
+	digits12 := make([]int, 12)
+	for i := 0; i < 8; i++ {
+		digit, err := cryptoRandInt(10)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random digit: %w", err)
+		}
+		digits12[i] = digit
+	}
+	digits12[11] = 1
+
+	dv, err := getCD(digits12)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate check digits: %w", err)
+	}
+	if invalid {
+		// Shift the first DV so the result is guaranteed to be invalid.
+		offset, err := cryptoRandInt(9)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random digit: %w", err)
+		}
+		dv[0] = (dv[0] + offset + 1) % 10
+	}
+
+	cnpjStr := strings.Builder{}
+	for _, d := range append(digits12, dv[0], dv[1]) {
+		cnpjStr.WriteString(strconv.Itoa(d))
+	}
+
+	if formatted {
+		return FormatCNPJ(cnpjStr.String())
+	}
+	return cnpjStr.String(), nil
+}
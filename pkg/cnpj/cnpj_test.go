@@ -0,0 +1,97 @@
+package cnpj
+
+import (
+	"testing"
+)
+
+func TestFormatCNPJ(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+	}{
+		{"valid unformatted", "11222333000181", "11.222.333/0001-81", false},
+		{"valid formatted", "11.222.333/0001-81", "11.222.333/0001-81", false},
+		{"too short", "1122233300018", "", true},
+		{"too long", "112223330001811", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatCNPJ(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Errorf("FormatCNPJ() error = %v, expectError %v", err, tt.expectError)
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("FormatCNPJ() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateCNPJ(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		byLength bool
+		expected bool
+	}{
+		{"valid CNPJ", "11222333000181", false, true},
+		{"valid formatted CNPJ", "11.222.333/0001-81", false, true},
+		{"valid Banco do Brasil", "00.000.000/0001-91", false, true},
+		{"valid Petrobras", "33.000.167/0001-01", false, true},
+		{"invalid check digit", "11222333000182", false, false},
+		{"repeated digits", "11111111111111", false, false},
+		{"too short", "1122233300018", false, false},
+		{"too long", "112223330001811", false, false},
+		{"by length valid", "11222333000182", true, true},
+		{"by length repeated", "11111111111111", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateCNPJ(tt.input, tt.byLength); got != tt.expected {
+				t.Errorf("ValidateCNPJ() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateCNPJ(t *testing.T) {
+	tests := []struct {
+		name       string
+		formatted  bool
+		invalid    bool
+		wantFormat bool
+		wantValid  bool
+	}{
+		{"valid unformatted", false, false, false, true},
+		{"valid formatted", true, false, true, true},
+		{"invalid unformatted", false, true, false, false},
+		{"invalid formatted", true, true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCNPJ(tt.formatted, tt.invalid)
+			if err != nil {
+				t.Errorf("GenerateCNPJ() error = %v", err)
+				return
+			}
+
+			if tt.wantFormat {
+				if len(got) != 18 || got[2] != '.' || got[6] != '.' || got[10] != '/' || got[15] != '-' {
+					t.Errorf("GenerateCNPJ() formatting incorrect = %v", got)
+				}
+			} else if len(got) != 14 {
+				t.Errorf("GenerateCNPJ() length incorrect = %v", got)
+			}
+
+			if isValid := ValidateCNPJ(got, false); isValid != tt.wantValid {
+				t.Errorf("GenerateCNPJ() validity = %v, want %v", isValid, tt.wantValid)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package cpf
+
+import "sync"
+
+// defaultBlocklist holds sequential CPFs that pass the check digit algorithm
+// and are commonly typed into forms as placeholders.
+var defaultBlocklist = []string{
+	"01234567890",
+	"12345678909",
+	"23456789092",
+	"98765432100",
+	"87654321007",
+}
+
+var (
+	blocklistMu sync.RWMutex
+	blocklist   = make(map[string]struct{})
+)
+
+func init() {
+	for _, cpf := range defaultBlocklist {
+		blocklist[cpf] = struct{}{}
+	}
+}
+
+// AddBlocklisted adds a CPF to the blocklist consulted by validators created
+// with WithRejectBlocklisted. Formatting is ignored.
+func AddBlocklisted(cpf string) {
+	blocklistMu.Lock()
+	defer blocklistMu.Unlock()
+	blocklist[UnformatCPF(cpf)] = struct{}{}
+}
+
+// IsBlocklisted reports whether the CPF is in the blocklist. Formatting is
+// ignored.
+func IsBlocklisted(cpf string) bool {
+	blocklistMu.RLock()
+	defer blocklistMu.RUnlock()
+	_, ok := blocklist[UnformatCPF(cpf)]
+	return ok
+}
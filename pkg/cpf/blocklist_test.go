@@ -0,0 +1,49 @@
+package cpf
+
+import "testing"
+
+func TestRejectBlocklisted(t *testing.T) {
+	const sample = "123.456.789-09"
+
+	if !ValidateCPF(sample, false) {
+		t.Fatalf("ValidateCPF(%q) = false, want the sample to pass the check digits", sample)
+	}
+
+	v := NewValidator(WithRejectBlocklisted(true))
+	valid, reason := v.ValidateDetailed(sample)
+	if valid || reason != ErrBlocklisted {
+		t.Errorf("ValidateDetailed(%q) = %v, %v; want false, blocklisted", sample, valid, reason)
+	}
+	if reason.String() != "blocklisted" {
+		t.Errorf("ErrBlocklisted.String() = %q", reason.String())
+	}
+
+	if !v.Validate("529.982.247-25") {
+		t.Error("Validate() rejected a CPF that is not blocklisted")
+	}
+}
+
+func TestAddBlocklisted(t *testing.T) {
+	const cpf = "453.178.287-91"
+	v := NewValidator(WithRejectBlocklisted(true))
+	if !v.Validate(cpf) {
+		t.Fatalf("Validate(%q) = false before blocklisting", cpf)
+	}
+
+	AddBlocklisted("45317828791")
+	t.Cleanup(func() {
+		blocklistMu.Lock()
+		delete(blocklist, "45317828791")
+		blocklistMu.Unlock()
+	})
+
+	if !IsBlocklisted(cpf) {
+		t.Errorf("IsBlocklisted(%q) = false after AddBlocklisted", cpf)
+	}
+	if v.Validate(cpf) {
+		t.Errorf("Validate(%q) = true after AddBlocklisted", cpf)
+	}
+	if !ValidateCPF(cpf, false) {
+		t.Errorf("ValidateCPF(%q) = false; the blocklist must be opt-in", cpf)
+	}
+}
@@ -0,0 +1,58 @@
+package cpf
+
+import "fmt"
+
+// ChunkedWriter writes results across numbered files named prefix-0001.ext,
+// prefix-0002.ext and so on, each holding at most size results, so that
+// large exports are sharded into manageable files. Results are buffered one
+// chunk at a time and handed to a writer such as WriteJSONOutput.
+type ChunkedWriter struct {
+	prefix string
+	ext    string
+	size   int
+	write  func(results []CPFResult, filename string) error
+	chunk  []CPFResult
+	files  []string
+}
+
+// NewChunkedWriter creates a ChunkedWriter that writes each chunk of at most
+// size results with write. ext is the file extension, including its dot.
+func NewChunkedWriter(prefix, ext string, size int, write func([]CPFResult, string) error) (*ChunkedWriter, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d (must be positive)", size)
+	}
+	return &ChunkedWriter{prefix: prefix, ext: ext, size: size, write: write}, nil
+}
+
+// Write adds a result to the current chunk, writing the chunk out once it
+// is full.
+func (c *ChunkedWriter) Write(result CPFResult) error {
+	c.chunk = append(c.chunk, result)
+	if len(c.chunk) < c.size {
+		return nil
+	}
+	return c.flush()
+}
+
+// Close writes out the last, partial chunk, if any.
+func (c *ChunkedWriter) Close() error {
+	if len(c.chunk) == 0 {
+		return nil
+	}
+	return c.flush()
+}
+
+// Files returns the names of the files written so far.
+func (c *ChunkedWriter) Files() []string {
+	return c.files
+}
+
+func (c *ChunkedWriter) flush() error {
+	filename := fmt.Sprintf("%s-%04d%s", c.prefix, len(c.files)+1, c.ext)
+	if err := c.write(c.chunk, filename); err != nil {
+		return err
+	}
+	c.files = append(c.files, filename)
+	c.chunk = c.chunk[:0]
+	return nil
+}
@@ -0,0 +1,55 @@
+package cpf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestChunkedWriter(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "cpfs")
+	w, err := NewChunkedWriter(prefix, ".json", 10, WriteJSONOutput)
+	if err != nil {
+		t.Fatalf("NewChunkedWriter() error = %v", err)
+	}
+	for i := 0; i < 25; i++ {
+		if err := w.Write(CPFResult{CPF: strconv.Itoa(i)}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	wantFiles := []string{prefix + "-0001.json", prefix + "-0002.json", prefix + "-0003.json"}
+	if !reflect.DeepEqual(w.Files(), wantFiles) {
+		t.Fatalf("Files() = %v, want %v", w.Files(), wantFiles)
+	}
+	next := 0
+	for i, name := range wantFiles {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []CPFResult
+		if err := json.Unmarshal(data, &results); err != nil {
+			t.Fatalf("%s is not a JSON array: %v", name, err)
+		}
+		if want := []int{10, 10, 5}[i]; len(results) != want {
+			t.Errorf("%s holds %d results, want %d", name, len(results), want)
+		}
+		for _, r := range results {
+			if r.CPF != strconv.Itoa(next) {
+				t.Errorf("%s holds %q, want %d", name, r.CPF, next)
+			}
+			next++
+		}
+	}
+
+	if _, err := NewChunkedWriter(prefix, ".json", 0, WriteJSONOutput); err == nil {
+		t.Error("NewChunkedWriter(size=0) expected error")
+	}
+}
@@ -2,52 +2,551 @@ package cpf
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
 )
 
 // CPFResult represents the result of a CPF operation
 type CPFResult struct {
-	CPF      string `json:"cpf"`
-	Valid    bool   `json:"valid,omitempty"`
-	Error    string `json:"error,omitempty"`
-	Original string `json:"original,omitempty"`
+	CPF      string `json:"cpf" xml:"cpf"`
+	Valid    bool   `json:"valid,omitempty" xml:"valid,omitempty"`
+	Error    string `json:"error,omitempty" xml:"error,omitempty"`
+	Reason   string `json:"reason,omitempty" xml:"reason,omitempty"`
+	Original string `json:"original,omitempty" xml:"original,omitempty"`
+	// Generated marks results of CPF generation, whose JSON always carries
+	// the valid field, even when false.
+	Generated bool `json:"generated,omitempty" xml:"generated,omitempty"`
+	// Fields holds the other columns of the CSV row the CPF was read from.
+	Fields map[string]string `json:"fields,omitempty" xml:"-"`
+	// Line is the 1-based line of the input the CPF was read from, counting
+	// blank lines. Zero means unknown.
+	Line int `json:"line,omitempty" xml:"line,omitempty"`
+	// Source is the file the CPF was read from, set by ProcessFiles.
+	Source string `json:"source,omitempty" xml:"source,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, keeping "valid": false in the output
+// of generated results.
+func (r CPFResult) MarshalJSON() ([]byte, error) {
+	type result CPFResult
+	if !r.Generated {
+		return json.Marshal(result(r))
+	}
+	return json.Marshal(struct {
+		result
+		Valid bool `json:"valid"`
+	}{result(r), r.Valid})
+}
+
+// GeneratedResult creates a CPFResult for a generated CPF, recording whether
+// it is valid
+func GeneratedResult(cpf string) CPFResult {
+	return CPFResult{
+		CPF:       cpf,
+		Valid:     ValidateCPF(cpf, false),
+		Generated: true,
+	}
+}
+
+// ProcessOptions controls how input files are read
+type ProcessOptions struct {
+	// Limit stops reading after this many non-empty lines. Zero means no limit.
+	Limit int
+	// Delimiter separates CPFs in the input. Empty means one CPF per line.
+	Delimiter string
+	// MaxErrors stops processing, with ErrMaxErrors, once this many results
+	// aren't valid. Zero means no limit.
+	MaxErrors int
+	// Encoding is the character encoding of the input, one of Encodings.
+	// Empty means UTF-8.
+	Encoding string
+	// SkipHeader ignores the first non-empty line, such as a "cpf" header.
+	// It doesn't count towards Limit.
+	SkipHeader bool
 }
 
+// Encodings are the input encodings accepted by ProcessOptions.Encoding
+var Encodings = []string{"utf8", "latin1"}
+
+// ErrMaxErrors is returned, along with the results collected so far, when
+// processing stops after ProcessOptions.MaxErrors invalid results
+var ErrMaxErrors = errors.New("exceeded max errors")
+
 // ProcessFile processes CPFs from a file using the provided processor function
 func ProcessFile(filename string, processFunc func(string) CPFResult) ([]CPFResult, error) {
-	file, err := os.Open(filename)
+	return ProcessFileContext(context.Background(), filename, processFunc)
+}
+
+// ProcessFileContext is like ProcessFile but stops early, returning ctx.Err(),
+// when ctx is cancelled
+func ProcessFileContext(ctx context.Context, filename string, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	return processFile(ctx, filename, ProcessOptions{}, processFunc)
+}
+
+// ProcessFileWithOptions processes CPFs from a file using the provided
+// processor function, honoring opts
+func ProcessFileWithOptions(filename string, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	return processFile(context.Background(), filename, opts, processFunc)
+}
+
+func processFile(ctx context.Context, filename string, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	file, err := openInput(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
+	return processReader(ctx, file, opts, processFunc)
+}
+
+// ProcessFiles processes CPFs from each file in turn using the provided
+// processor function, tagging every result with the file it came from
+func ProcessFiles(filenames []string, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	return ProcessFilesWithOptions(filenames, ProcessOptions{}, processFunc)
+}
+
+// ProcessFilesWithOptions is like ProcessFiles but honors opts, which apply
+// to each file separately
+func ProcessFilesWithOptions(filenames []string, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	var results []CPFResult
+	for _, filename := range filenames {
+		fileResults, err := processFile(context.Background(), filename, opts, processFunc)
+		if err != nil && !errors.Is(err, ErrMaxErrors) {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for i := range fileResults {
+			fileResults[i].Source = filename
+		}
+		results = append(results, fileResults...)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// ProcessFileDelimited processes CPFs from a file separated by delim instead
+// of newlines, using the provided processor function
+func ProcessFileDelimited(filename, delim string, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	return processFile(context.Background(), filename, ProcessOptions{Delimiter: delim}, processFunc)
+}
+
+// ProcessFileUntilInvalid is like ProcessFileWithOptions but stops reading at
+// the first result that isn't valid, which is the last one returned
+func ProcessFileUntilInvalid(filename string, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []CPFResult
+	err = scanLines(context.Background(), file, opts, func(n int, line string, size int) bool {
+		result := processLine(line, size, processFunc)
+		result.Line = n
+		results = append(results, result)
+		return result.Valid
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ProcessReader processes CPFs from a reader (one per line) using the provided
+// processor function
+func ProcessReader(r io.Reader, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	return ProcessReaderWithOptions(r, ProcessOptions{}, processFunc)
+}
+
+// ProcessReaderWithOptions processes CPFs from a reader (one per line) using
+// the provided processor function, honoring opts
+func ProcessReaderWithOptions(r io.Reader, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	return processReader(context.Background(), r, opts, processFunc)
+}
+
+func processReader(ctx context.Context, r io.Reader, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
 	var results []CPFResult
-	scanner := bufio.NewScanner(file)
+	invalid := 0
+	err := scanLines(ctx, r, opts, func(n int, line string, size int) bool {
+		result := processLine(line, size, processFunc)
+		result.Line = n
+		results = append(results, result)
+		if !result.Valid {
+			invalid++
+		}
+		return opts.MaxErrors == 0 || invalid < opts.MaxErrors
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxErrors > 0 && invalid >= opts.MaxErrors {
+		return results, ErrMaxErrors
+	}
+	return results, nil
+}
+
+// maxLineLength is the longest line processed as a CPF. Anything longer,
+// such as a line of a binary file, is reported as malformed instead
+const maxLineLength = 64
+
+// maxScanLine is the most scanLines buffers of a line; the rest of a longer
+// line is skipped
+const maxScanLine = 1 << 20
+
+// processLine runs processFunc on line, whose full size is size bytes. A line
+// too long to be a CPF isn't processed but returned as malformed, cut short
+func processLine(line string, size int, processFunc func(string) CPFResult) CPFResult {
+	if size <= maxLineLength {
+		return processFunc(line)
+	}
+	return CPFResult{
+		CPF:    strings.ToValidUTF8(line[:min(len(line), maxLineLength)], "") + "...",
+		Error:  fmt.Sprintf("line too long to be a CPF (%d bytes, at most %d); is this a binary file?", size, maxLineLength),
+		Reason: ErrLength.String(),
+	}
+}
+
+// ReadLines reads the non-empty lines of a file, trimmed of surrounding
+// whitespace, without processing them. Lines longer than maxScanLine are cut
+// short
+func ReadLines(filename string, opts ProcessOptions) ([]string, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadLinesFrom(file, opts)
+}
+
+// ReadLinesFrom is like ReadLines but reads from r
+func ReadLinesFrom(r io.Reader, opts ProcessOptions) ([]string, error) {
+	var lines []string
+	err := scanLines(context.Background(), r, opts, func(_ int, line string, _ int) bool {
+		lines = append(lines, line)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// gzipMagic are the first bytes of every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// inputFile reads a possibly gzip-compressed file
+type inputFile struct {
+	io.Reader
+	file *os.File
+	gz   *gzip.Reader
+}
+
+// Close closes the gzip reader, if any, and the underlying file
+func (f *inputFile) Close() error {
+	if f.gz != nil {
+		f.gz.Close()
+	}
+	return f.file.Close()
+}
+
+// openInput opens filename for reading, transparently decompressing it when
+// it starts with the gzip magic bytes
+func openInput(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	buffered := bufio.NewReader(file)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(magic, gzipMagic) {
+		return &inputFile{Reader: buffered, file: file}, nil
+	}
+
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read gzip file: %w", err)
+	}
+	return &inputFile{Reader: gz, file: file, gz: gz}, nil
+}
+
+// scanLines calls fn for every non-empty line of r, trimmed of surrounding
+// whitespace, along with its 1-based line number and its size in bytes, until
+// fn returns false. Lines are split on opts.Delimiter when set. A line longer
+// than maxScanLine is passed cut short, with its full size. It returns
+// ctx.Err() as soon as ctx is cancelled
+func scanLines(ctx context.Context, r io.Reader, opts ProcessOptions, fn func(int, string, int) bool) error {
+	r, err := decodeInput(r, opts.Encoding)
+	if err != nil {
+		return err
+	}
+	split := bufio.ScanLines
+	if opts.Delimiter != "" && opts.Delimiter != "\n" {
+		split = splitOn(opts.Delimiter)
+	}
+	long := &longTokenSplitter{split: split, max: maxScanLine}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxScanLine)
+	scanner.Split(long.Split)
+	processed := 0
+	n := 0
+	skipHeader := opts.SkipHeader
 	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n++
+
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
-		results = append(results, processFunc(line))
+		if skipHeader {
+			skipHeader = false
+			continue
+		}
+		if opts.Limit > 0 && processed >= opts.Limit {
+			break
+		}
+		if !fn(n, line, max(len(line), long.skipped)) {
+			break
+		}
+		processed++
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		return fmt.Errorf("error reading input: %w", err)
 	}
+	return nil
+}
 
-	return results, nil
+// longTokenSplitter wraps a bufio.SplitFunc so that a token that doesn't fit
+// in the scanner buffer is returned cut to its first max bytes, the rest
+// being skipped, instead of failing with bufio.ErrTooLong
+type longTokenSplitter struct {
+	split bufio.SplitFunc
+	max   int
+	head  []byte // start of the token being skipped, if any
+	// skipped is the full size of the last token returned when it was cut
+	// short, and zero otherwise
+	skipped int
+}
+
+// Split implements bufio.SplitFunc
+func (s *longTokenSplitter) Split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := s.split(data, atEOF)
+	if err != nil {
+		return advance, token, err
+	}
+	if s.head == nil {
+		s.skipped = 0
+		if advance == 0 && token == nil && len(data) >= s.max {
+			// The token doesn't fit: keep its start and skip the rest
+			s.head = append([]byte(nil), data[:s.max]...)
+			s.skipped = len(data)
+			return len(data), nil, nil
+		}
+		return advance, token, err
+	}
+
+	if advance == 0 && token == nil {
+		if atEOF {
+			return s.finish(len(data), 0)
+		}
+		s.skipped += len(data)
+		return len(data), nil, nil
+	}
+	return s.finish(advance, len(token))
+}
+
+// finish ends the token being skipped, returning its start
+func (s *longTokenSplitter) finish(advance, tail int) (int, []byte, error) {
+	token := s.head
+	s.head = nil
+	s.skipped += tail
+	return advance, token, nil
+}
+
+// decodeInput returns a reader of r as UTF-8, decoding it from encoding
+func decodeInput(r io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "", "utf8":
+		return r, nil
+	case "latin1":
+		return charmap.ISO8859_1.NewDecoder().Reader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported input encoding '%s'", encoding)
+}
+
+// splitOn returns a bufio.SplitFunc that splits tokens on delim
+func splitOn(delim string) bufio.SplitFunc {
+	sep := []byte(delim)
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, sep); i >= 0 {
+			return i + len(sep), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// ValidateAll validates CPFs concurrently using up to workers goroutines. The
+// returned results are in the same order as the input. CPFs too long to be
+// one are reported as malformed, like the lines of a processed file.
+func ValidateAll(cpfs []string, workers int) []CPFResult {
+	results := make([]CPFResult, len(cpfs))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(cpfs) {
+		workers = len(cpfs)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = processLine(cpfs[i], len(cpfs[i]), ValidateProcessor)
+			}
+		}()
+	}
+
+	for i := range cpfs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
 }
 
 // ValidateProcessor creates a CPFResult for validation
 func ValidateProcessor(cpf string) CPFResult {
-	return CPFResult{
+	valid, reason := ValidateCPFDetailed(cpf)
+	result := CPFResult{
 		CPF:      cpf,
-		Valid:    ValidateCPF(cpf, false),
+		Valid:    valid,
 		Original: cpf,
 	}
+	if !valid {
+		result.Reason = reason.String()
+	}
+	if reason == ErrLength {
+		result.Error = lengthError(len(UnformatCPF(cpf))).Error()
+	}
+	return result
+}
+
+// AllValid reports whether every result is a valid CPF
+func AllValid(results []CPFResult) bool {
+	for _, r := range results {
+		if !r.Valid {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary counts the outcomes of a validation run
+type Summary struct {
+	Total   int `json:"total"`
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+	// Malformed counts the invalid results that don't even have 11 digits.
+	Malformed int `json:"malformed"`
+}
+
+// String returns the summary as "total=N valid=N invalid=N"
+func (s Summary) String() string {
+	return fmt.Sprintf("total=%d valid=%d invalid=%d", s.Total, s.Valid, s.Invalid)
+}
+
+// Summarize counts the valid and invalid results
+func Summarize(results []CPFResult) Summary {
+	summary := Summary{Total: len(results)}
+	for _, r := range results {
+		switch {
+		case r.Valid:
+			summary.Valid++
+		case isMalformed(r):
+			summary.Malformed++
+		}
+	}
+	summary.Invalid = summary.Total - summary.Valid
+	return summary
+}
+
+// SortResults sorts results in place by their CPF as digits only, so that
+// formatting doesn't affect the order, in descending order when desc is set.
+// Results with the same digits keep their relative order.
+func SortResults(results []CPFResult, desc bool) {
+	slices.SortStableFunc(results, func(a, b CPFResult) int {
+		c := strings.Compare(UnformatCPF(a.CPF), UnformatCPF(b.CPF))
+		if desc {
+			return -c
+		}
+		return c
+	})
+}
+
+// isMalformed reports whether r is invalid for not even having 11 digits
+func isMalformed(r CPFResult) bool {
+	return r.Reason == ErrLength.String() || r.Reason == ErrNonNumeric.String()
+}
+
+// PartitionResults splits results into the valid ones, the ones with 11
+// digits that are invalid, and the malformed ones that don't have 11 digits,
+// preserving their order. Every result lands in exactly one of them.
+func PartitionResults(results []CPFResult) (valid, invalid, malformed []CPFResult) {
+	for _, r := range results {
+		switch {
+		case r.Valid:
+			valid = append(valid, r)
+		case isMalformed(r):
+			malformed = append(malformed, r)
+		default:
+			invalid = append(invalid, r)
+		}
+	}
+	return valid, invalid, malformed
+}
+
+// FilterResults returns the results whose validity matches valid, preserving
+// their order
+func FilterResults(results []CPFResult, valid bool) []CPFResult {
+	filtered := make([]CPFResult, 0, len(results))
+	for _, r := range results {
+		if r.Valid == valid {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
 }
 
 // FormatProcessor creates a CPFResult for formatting
@@ -66,33 +565,555 @@ func FormatProcessor(cpf string) CPFResult {
 	}
 }
 
-// GenerateCPFsJSON generates multiple CPFs in JSON format
+// CustomFormatProcessor returns a processor like FormatProcessor that formats
+// CPFs with FormatCPFCustom and the given separators
+func CustomFormatProcessor(groupSep, dvSep string) func(string) CPFResult {
+	return func(cpf string) CPFResult {
+		formatted, err := FormatCPFCustom(cpf, groupSep, dvSep)
+		if err != nil {
+			return CPFResult{
+				CPF:      cpf,
+				Error:    err.Error(),
+				Original: cpf,
+			}
+		}
+		return CPFResult{
+			CPF:      formatted,
+			Original: cpf,
+		}
+	}
+}
+
+// NormalizeProcessor creates a CPFResult holding the canonical
+// ###.###.###-## form of a CPF. Lines that don't contain exactly 11 digits are
+// passed through untouched with an explanatory error
+func NormalizeProcessor(cpf string) CPFResult {
+	return normalize(cpf, true)
+}
+
+// NormalizeDigitsProcessor is like NormalizeProcessor but canonicalizes CPFs
+// to digits only
+func NormalizeDigitsProcessor(cpf string) CPFResult {
+	return normalize(cpf, false)
+}
+
+func normalize(cpf string, formatted bool) CPFResult {
+	digits := UnformatCPF(cpf)
+	if len(digits) != 11 {
+		return CPFResult{
+			CPF:      cpf,
+			Error:    fmt.Sprintf("not normalized: found %d digits, expected 11", len(digits)),
+			Original: cpf,
+		}
+	}
+
+	normalized := digits
+	if formatted {
+		normalized, _ = FormatCPF(digits)
+	}
+	return CPFResult{
+		CPF:      normalized,
+		Original: cpf,
+	}
+}
+
+// Dedupe removes repeated CPFs, keeping the first occurrence of each. CPFs
+// are compared by their digits, so differently formatted copies of the same
+// CPF collapse into one. It returns the unique CPFs in first-seen order and
+// the number of duplicates dropped.
+func Dedupe(cpfs []string) (unique []string, dupes int) {
+	seen := make(map[string]bool, len(cpfs))
+	for _, cpf := range cpfs {
+		key := diffKey(cpf)
+		if seen[key] {
+			dupes++
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, cpf)
+	}
+	return unique, dupes
+}
+
+// DiffResult holds the differences between two lists of CPFs
+type DiffResult struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Common  []string `json:"common"`
+}
+
+// DiffCPFs compares two lists of CPFs by their digits, so formatting
+// differences are ignored. Added holds the CPFs only in new, Removed the ones
+// only in old and Common the ones in both. CPFs are reported in the
+// ###.###.###-## format, once each, in the order they first appear.
+func DiffCPFs(old, new []string) DiffResult {
+	oldUnique, _ := Dedupe(old)
+	newUnique, _ := Dedupe(new)
+
+	inOld := make(map[string]bool, len(oldUnique))
+	for _, cpf := range oldUnique {
+		inOld[diffKey(cpf)] = true
+	}
+	inNew := make(map[string]bool, len(newUnique))
+	for _, cpf := range newUnique {
+		inNew[diffKey(cpf)] = true
+	}
+
+	result := DiffResult{Added: []string{}, Removed: []string{}, Common: []string{}}
+	for _, cpf := range oldUnique {
+		if inNew[diffKey(cpf)] {
+			result.Common = append(result.Common, NormalizeProcessor(cpf).CPF)
+		} else {
+			result.Removed = append(result.Removed, NormalizeProcessor(cpf).CPF)
+		}
+	}
+	for _, cpf := range newUnique {
+		if !inOld[diffKey(cpf)] {
+			result.Added = append(result.Added, NormalizeProcessor(cpf).CPF)
+		}
+	}
+	return result
+}
+
+// diffKey returns the key CPFs are compared by in Dedupe and DiffCPFs
+func diffKey(cpf string) string {
+	if key := UnformatCPF(cpf); key != "" {
+		return key
+	}
+	return cpf
+}
+
+// GenerateCPFsJSON generates multiple distinct CPFs in JSON format
 func GenerateCPFsJSON(count int, formatted, invalid bool) ([]CPFResult, error) {
+	cpfs, err := GenerateUnique(count, func() (string, error) {
+		return GenerateCPF(formatted, invalid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	results := make([]CPFResult, 0, count)
-	for i := 0; i < count; i++ {
-		cpf, err := GenerateCPF(formatted, invalid)
+	for _, cpf := range cpfs {
+		results = append(results, GeneratedResult(cpf))
+	}
+	return results, nil
+}
+
+// GenerateCPFsMap generates count distinct CPFs keyed by their index, from
+// "0" to count-1, for tools that expect a JSON object rather than an array
+func GenerateCPFsMap(count int, formatted, invalid bool) (map[string]string, error) {
+	cpfs, err := GenerateUnique(count, func() (string, error) {
+		return GenerateCPF(formatted, invalid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return IndexCPFs(cpfs), nil
+}
+
+// IndexCPFs maps the decimal index of each CPF in cpfs to the CPF
+func IndexCPFs(cpfs []string) map[string]string {
+	m := make(map[string]string, len(cpfs))
+	for i, cpf := range cpfs {
+		m[strconv.Itoa(i)] = cpf
+	}
+	return m
+}
+
+// WriteJSONObject writes cpfs to w as an indented JSON object keyed by their
+// index, like the JSON encoding of IndexCPFs but with the keys in numeric
+// rather than lexical order, followed by a newline
+func WriteJSONObject(w io.Writer, cpfs []string) error {
+	bw := bufio.NewWriter(w)
+	if len(cpfs) == 0 {
+		bw.WriteString("{}\n")
+	}
+	for i, cpf := range cpfs {
+		value, err := json.Marshal(cpf)
+		if err != nil {
+			return fmt.Errorf("error marshaling JSON: %w", err)
+		}
+		sep := ",\n  "
+		if i == 0 {
+			sep = "{\n  "
+		}
+		fmt.Fprintf(bw, "%s\"%d\": %s", sep, i, value)
+	}
+	if len(cpfs) > 0 {
+		bw.WriteString("\n}\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// maxUniqueRetries caps how many consecutive duplicates GenerateUnique
+// tolerates before giving up.
+const maxUniqueRetries = 1000
+
+// GenerateUnique calls next until it has produced count distinct CPFs,
+// regenerating on collisions. It fails if next keeps returning CPFs that were
+// already emitted, which happens when the space of possible values is smaller
+// than count.
+func GenerateUnique(count int, next func() (string, error)) ([]string, error) {
+	cpfs := make([]string, 0, count)
+	for cpf, err := range GenerateUniqueStream(count, next) {
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, CPFResult{CPF: cpf})
+		cpfs = append(cpfs, cpf)
 	}
-	return results, nil
+	return cpfs, nil
 }
 
 // WriteJSONOutput writes JSON results to a file or stdout
 func WriteJSONOutput(results []CPFResult, outputFile string) error {
-	output, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return fmt.Errorf("error marshaling JSON: %w", err)
-	}
+	return writeJSONOutput(results, outputFile, false)
+}
+
+// WriteJSONOutputCompact is like WriteJSONOutput but writes the results on a
+// single line, without indentation
+func WriteJSONOutputCompact(results []CPFResult, outputFile string) error {
+	return writeJSONOutput(results, outputFile, true)
+}
 
+func writeJSONOutput(results []CPFResult, outputFile string, compact bool) error {
 	if outputFile != "" {
+		output, err := marshalJSON(results, compact)
+		if err != nil {
+			return err
+		}
 		if err := os.WriteFile(outputFile, output, 0644); err != nil {
 			return fmt.Errorf("error writing to file: %w", err)
 		}
 		return nil
 	}
 
-	fmt.Println(string(output))
+	return writeJSON(os.Stdout, results, compact)
+}
+
+// WriteJSON writes JSON results to w, followed by a newline
+func WriteJSON(w io.Writer, results []CPFResult) error {
+	return writeJSON(w, results, false)
+}
+
+// WriteJSONCompact writes JSON results to w on a single line, followed by a
+// newline
+func WriteJSONCompact(w io.Writer, results []CPFResult) error {
+	return writeJSON(w, results, true)
+}
+
+func writeJSON(w io.Writer, results []CPFResult, compact bool) error {
+	output, err := marshalJSON(results, compact)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, string(output)); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// marshalJSON encodes results as a JSON array, indented unless compact is set
+func marshalJSON(results []CPFResult, compact bool) ([]byte, error) {
+	var output []byte
+	var err error
+	if compact {
+		output, err = json.Marshal(results)
+	} else {
+		output, err = json.MarshalIndent(results, "", "  ")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+	}
+	return output, nil
+}
+
+// Envelope wraps results with the provenance written by WriteJSONEnvelope
+type Envelope struct {
+	Version     string      `json:"version"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Count       int         `json:"count"`
+	Results     []CPFResult `json:"results"`
+}
+
+// WriteJSONEnvelope writes results to a file or stdout as a JSON object
+// recording the tool version, the time of writing and the number of results
+func WriteJSONEnvelope(results []CPFResult, version, outputFile string) error {
+	if outputFile == "" {
+		return WriteJSONEnvelopeTo(os.Stdout, results, version)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSONEnvelopeTo(&buf, results, version); err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	return nil
+}
+
+// WriteJSONEnvelopeTo writes results to w as a JSON envelope, followed by a
+// newline
+func WriteJSONEnvelopeTo(w io.Writer, results []CPFResult, version string) error {
+	if results == nil {
+		results = []CPFResult{}
+	}
+	envelope := Envelope{
+		Version:     version,
+		GeneratedAt: time.Now().UTC(),
+		Count:       len(results),
+		Results:     results,
+	}
+	output, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	if _, err := fmt.Fprintln(w, string(output)); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
 	return nil
-} 
\ No newline at end of file
+}
+
+// WriteJSONStream writes results to a file or stdout as a JSON array as they
+// arrive on the channel, so memory use stays flat regardless of count. The
+// output is identical to WriteJSONOutput's.
+func WriteJSONStream(results <-chan CPFResult, outputFile string) error {
+	return writeStream(results, outputFile, WriteJSONStreamTo)
+}
+
+// writeStream writes results to a file or stdout using writeTo
+func writeStream(results <-chan CPFResult, outputFile string, writeTo func(io.Writer, <-chan CPFResult) error) error {
+	if outputFile == "" {
+		return writeTo(os.Stdout, results)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if err := writeTo(file, results); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	return nil
+}
+
+// WriteJSONStreamTo writes results to w as a JSON array as they arrive on the
+// channel, followed by a newline
+func WriteJSONStreamTo(w io.Writer, results <-chan CPFResult) error {
+	return writeJSONStreamTo(w, results, false)
+}
+
+// WriteJSONCompactStream is like WriteJSONStream but writes the array on a
+// single line. The output is identical to WriteJSONOutputCompact's.
+func WriteJSONCompactStream(results <-chan CPFResult, outputFile string) error {
+	return writeStream(results, outputFile, WriteJSONCompactStreamTo)
+}
+
+// WriteJSONCompactStreamTo writes results to w as a single-line JSON array as
+// they arrive on the channel, followed by a newline
+func WriteJSONCompactStreamTo(w io.Writer, results <-chan CPFResult) error {
+	return writeJSONStreamTo(w, results, true)
+}
+
+func writeJSONStreamTo(w io.Writer, results <-chan CPFResult, compact bool) error {
+	bw := bufio.NewWriter(w)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	open, sep, end := "[\n  ", ",\n  ", "\n]\n"
+	if compact {
+		open, sep, end = "[", ",", "]\n"
+	} else {
+		enc.SetIndent("  ", "  ")
+	}
+
+	count := 0
+	for result := range results {
+		buf.Reset()
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("error marshaling JSON: %w", err)
+		}
+
+		if count == 0 {
+			bw.WriteString(open)
+		} else {
+			bw.WriteString(sep)
+		}
+		bw.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		count++
+	}
+
+	if count == 0 {
+		bw.WriteString("[]\n")
+	} else {
+		bw.WriteString(end)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// WriteJSONLOutput writes results to a file or stdout as JSON Lines: one
+// compact JSON object per line
+func WriteJSONLOutput(results []CPFResult, outputFile string) error {
+	return WriteJSONLStream(sliceChan(results), outputFile)
+}
+
+// WriteJSONL writes results to w as JSON Lines
+func WriteJSONL(w io.Writer, results []CPFResult) error {
+	return WriteJSONLStreamTo(w, sliceChan(results))
+}
+
+// WriteJSONLStream writes results to a file or stdout as JSON Lines as they
+// arrive on the channel
+func WriteJSONLStream(results <-chan CPFResult, outputFile string) error {
+	return writeStream(results, outputFile, WriteJSONLStreamTo)
+}
+
+// WriteJSONLStreamTo writes results to w as JSON Lines as they arrive on the
+// channel
+func WriteJSONLStreamTo(w io.Writer, results <-chan CPFResult) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for result := range results {
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("error marshaling JSON: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// WriteXMLOutput writes results to a file or stdout as an XML document with
+// a <cpfs> root holding one <cpf> element per result
+func WriteXMLOutput(results []CPFResult, outputFile string) error {
+	return WriteXMLStream(sliceChan(results), outputFile)
+}
+
+// WriteXMLStream writes results to a file or stdout as an XML document as
+// they arrive on the channel
+func WriteXMLStream(results <-chan CPFResult, outputFile string) error {
+	return writeStream(results, outputFile, WriteXMLStreamTo)
+}
+
+// WriteXML writes results to w as an XML document
+func WriteXML(w io.Writer, results []CPFResult) error {
+	return WriteXMLStreamTo(w, sliceChan(results))
+}
+
+// WriteXMLStreamTo writes results to w as an XML document as they arrive on
+// the channel, followed by a newline
+func WriteXMLStreamTo(w io.Writer, results <-chan CPFResult) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+	root := xml.StartElement{Name: xml.Name{Local: "cpfs"}}
+	if err := enc.EncodeToken(root); err != nil {
+		return fmt.Errorf("error marshaling XML: %w", err)
+	}
+	for result := range results {
+		if err := enc.EncodeElement(result, xml.StartElement{Name: xml.Name{Local: "cpf"}}); err != nil {
+			return fmt.Errorf("error marshaling XML: %w", err)
+		}
+	}
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return fmt.Errorf("error marshaling XML: %w", err)
+	}
+	if err := enc.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+
+	bw.WriteString("\n")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// WritePlainOutput writes results to a file or stdout as tab-separated lines
+// holding the CPF and whether it is valid
+func WritePlainOutput(results []CPFResult, outputFile string) error {
+	return WritePlainStream(sliceChan(results), outputFile)
+}
+
+// WritePlain writes results to w as tab-separated lines
+func WritePlain(w io.Writer, results []CPFResult) error {
+	return WritePlainStreamTo(w, sliceChan(results))
+}
+
+// WritePlainStream writes results to a file or stdout as tab-separated lines
+// as they arrive on the channel
+func WritePlainStream(results <-chan CPFResult, outputFile string) error {
+	return writeStream(results, outputFile, WritePlainStreamTo)
+}
+
+// WritePlainStreamTo writes results to w as tab-separated lines as they
+// arrive on the channel
+func WritePlainStreamTo(w io.Writer, results <-chan CPFResult) error {
+	bw := bufio.NewWriter(w)
+	for result := range results {
+		fmt.Fprintf(bw, "%s\t%t\n", result.CPF, result.Valid)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// RenderTemplate renders each result through the text/template tmpl, such as
+// "{{.CPF}} -> {{.Valid}}", writing one line per result to w
+func RenderTemplate(results []CPFResult, tmpl string, w io.Writer) error {
+	return RenderTemplateStreamTo(w, sliceChan(results), tmpl)
+}
+
+// RenderTemplateStream writes results rendered through tmpl to a file or
+// stdout as they arrive on the channel
+func RenderTemplateStream(results <-chan CPFResult, tmpl, outputFile string) error {
+	return writeStream(results, outputFile, func(w io.Writer, results <-chan CPFResult) error {
+		return RenderTemplateStreamTo(w, results, tmpl)
+	})
+}
+
+// RenderTemplateStreamTo writes results rendered through tmpl to w as they
+// arrive on the channel, one line per result
+func RenderTemplateStreamTo(w io.Writer, results <-chan CPFResult, tmpl string) error {
+	t, err := template.New("output").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	for result := range results {
+		if err := t.Execute(bw, result); err != nil {
+			return fmt.Errorf("error rendering template: %w", err)
+		}
+		bw.WriteString("\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
+
+// sliceChan returns a closed channel holding results
+func sliceChan(results []CPFResult) <-chan CPFResult {
+	ch := make(chan CPFResult, len(results))
+	for _, r := range results {
+		ch <- r
+	}
+	close(ch)
+	return ch
+}
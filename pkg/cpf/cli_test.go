@@ -0,0 +1,978 @@
+package cpf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessReader(t *testing.T) {
+	input := bytes.NewBufferString("111.444.777-35\n\n  11144477734  \n123\n")
+
+	results, err := ProcessReader(input, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessReader() error = %v", err)
+	}
+
+	want := []struct {
+		cpf   string
+		valid bool
+	}{
+		{"111.444.777-35", true},
+		{"11144477734", false},
+		{"123", false},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("ProcessReader() returned %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].CPF != w.cpf || results[i].Valid != w.valid {
+			t.Errorf("result[%d] = {%v, %v}, want {%v, %v}", i, results[i].CPF, results[i].Valid, w.cpf, w.valid)
+		}
+	}
+}
+
+func TestValidateProcessorReason(t *testing.T) {
+	if got := ValidateProcessor("11144477735"); got.Reason != "" {
+		t.Errorf("ValidateProcessor() reason = %q for valid CPF, want empty", got.Reason)
+	}
+	if got := ValidateProcessor("11144477734"); got.Reason != "check_digit" {
+		t.Errorf("ValidateProcessor() reason = %q, want %q", got.Reason, "check_digit")
+	}
+}
+
+func TestGenerateCPFsJSONUnique(t *testing.T) {
+	const count = 5000
+	results, err := GenerateCPFsJSON(count, false, false)
+	if err != nil {
+		t.Fatalf("GenerateCPFsJSON() error = %v", err)
+	}
+
+	seen := make(map[string]struct{}, count)
+	for _, r := range results {
+		seen[r.CPF] = struct{}{}
+	}
+	if len(seen) != count {
+		t.Errorf("GenerateCPFsJSON() produced %d unique CPFs, want %d", len(seen), count)
+	}
+}
+
+func TestGenerateCPFsJSONValidity(t *testing.T) {
+	for _, invalid := range []bool{false, true} {
+		results, err := GenerateCPFsJSON(500, true, invalid)
+		if err != nil {
+			t.Fatalf("GenerateCPFsJSON(invalid=%v) error = %v", invalid, err)
+		}
+		for _, r := range results {
+			if r.Valid == invalid || !r.Generated {
+				t.Fatalf("GenerateCPFsJSON(invalid=%v) result %+v", invalid, r)
+			}
+		}
+
+		output, err := json.Marshal(results[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf(`"valid":%v`, !invalid)
+		if !strings.Contains(string(output), want) {
+			t.Errorf("JSON %s does not contain %s", output, want)
+		}
+	}
+
+	// Results that aren't generated keep omitting "valid": false
+	output, err := json.Marshal(CPFResult{CPF: "123"})
+	if err != nil || string(output) != `{"cpf":"123"}` {
+		t.Errorf("json.Marshal() = %s (error %v)", output, err)
+	}
+}
+
+func TestGenerateUniqueRetryCap(t *testing.T) {
+	next := func() (string, error) { return "11144477735", nil }
+	if _, err := GenerateUnique(2, next); err == nil {
+		t.Error("GenerateUnique() expected error when only duplicates are produced")
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	var cpfs []string
+	for i := 0; i < 1000; i++ {
+		generated, err := GenerateCPF(i%2 == 0, i%3 == 0)
+		if err != nil {
+			t.Fatalf("GenerateCPF() error = %v", err)
+		}
+		cpfs = append(cpfs, generated)
+	}
+	cpfs = append(cpfs, "123", "11111111111", "")
+
+	for _, workers := range []int{0, 1, 4, 16, 5000} {
+		results := ValidateAll(cpfs, workers)
+		if len(results) != len(cpfs) {
+			t.Fatalf("ValidateAll(workers=%d) returned %d results, want %d", workers, len(results), len(cpfs))
+		}
+		for i, input := range cpfs {
+			if want := ValidateProcessor(input); !reflect.DeepEqual(results[i], want) {
+				t.Errorf("ValidateAll(workers=%d)[%d] = %+v, want %+v", workers, i, results[i], want)
+			}
+		}
+	}
+}
+
+func TestAllValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []CPFResult
+		expected bool
+	}{
+		{"empty", nil, true},
+		{"all valid", []CPFResult{{Valid: true}, {Valid: true}}, true},
+		{"one invalid", []CPFResult{{Valid: true}, {Valid: false}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AllValid(tt.results); got != tt.expected {
+				t.Errorf("AllValid() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []CPFResult
+		want    Summary
+	}{
+		{"empty", nil, Summary{}},
+		{"mixed", []CPFResult{{Valid: true}, {}, {Valid: true}, {}, {}}, Summary{Total: 5, Valid: 2, Invalid: 3}},
+		{"all valid", []CPFResult{{Valid: true}, {Valid: true}}, Summary{Total: 2, Valid: 2}},
+		{"malformed", []CPFResult{{Valid: true}, {Reason: "length"}, {Reason: "check_digit"}, {Reason: "non_numeric"}}, Summary{Total: 4, Valid: 1, Invalid: 3, Malformed: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.results); got != tt.want {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	got := Summary{Total: 1000, Valid: 843, Invalid: 157}.String()
+	if want := "total=1000 valid=843 invalid=157"; got != want {
+		t.Errorf("Summary.String() = %q, want %q", got, want)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	input := []string{
+		"529.982.247-25",
+		"52998224725",
+		"111.444.777-35",
+		"529 982 247 25",
+		"11144477735",
+		"123",
+		"123",
+	}
+
+	unique, dupes := Dedupe(input)
+	want := []string{"529.982.247-25", "111.444.777-35", "123"}
+	if len(unique) != len(want) {
+		t.Fatalf("Dedupe() unique = %v, want %v", unique, want)
+	}
+	for i := range want {
+		if unique[i] != want[i] {
+			t.Errorf("Dedupe() unique[%d] = %q, want %q", i, unique[i], want[i])
+		}
+	}
+	if dupes != 4 {
+		t.Errorf("Dedupe() dupes = %d, want 4", dupes)
+	}
+}
+
+func TestDiffCPFs(t *testing.T) {
+	tests := []struct {
+		name string
+		old  []string
+		new  []string
+		want DiffResult
+	}{
+		{
+			name: "overlapping",
+			old:  []string{"529.982.247-25", "11144477735", "123"},
+			new:  []string{"52998224725", "111.444.777-35", "453.178.287-91"},
+			want: DiffResult{
+				Added:   []string{"453.178.287-91"},
+				Removed: []string{"123"},
+				Common:  []string{"529.982.247-25", "111.444.777-35"},
+			},
+		},
+		{
+			name: "disjoint",
+			old:  []string{"52998224725"},
+			new:  []string{"11144477735"},
+			want: DiffResult{
+				Added:   []string{"111.444.777-35"},
+				Removed: []string{"529.982.247-25"},
+				Common:  []string{},
+			},
+		},
+		{
+			name: "duplicates within a side",
+			old:  []string{"52998224725", "529.982.247-25"},
+			new:  []string{"529 982 247 25"},
+			want: DiffResult{
+				Added:   []string{},
+				Removed: []string{},
+				Common:  []string{"529.982.247-25"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffCPFs(tt.old, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffCPFs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	results := []CPFResult{
+		{CPF: "a", Valid: true},
+		{CPF: "b"},
+		{CPF: "c", Valid: true},
+		{CPF: "d"},
+	}
+
+	tests := []struct {
+		name  string
+		valid bool
+		want  []string
+	}{
+		{"valid", true, []string{"a", "c"}},
+		{"invalid", false, []string{"b", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterResults(results, tt.valid)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FilterResults() = %+v, want %v", got, tt.want)
+			}
+			for i, r := range got {
+				if r.CPF != tt.want[i] {
+					t.Errorf("FilterResults()[%d] = %q, want %q", i, r.CPF, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessFileWithOptionsLimit(t *testing.T) {
+	var content strings.Builder
+	for i := 0; i < 10; i++ {
+		generated, err := GenerateCPF(true, false)
+		if err != nil {
+			t.Fatalf("GenerateCPF() error = %v", err)
+		}
+		content.WriteString(generated + "\n")
+	}
+	filename := filepath.Join(t.TempDir(), "cpfs.txt")
+	if err := os.WriteFile(filename, []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"no limit", 0, 10},
+		{"limit 3", 3, 3},
+		{"limit above line count", 50, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ProcessFileWithOptions(filename, ProcessOptions{Limit: tt.limit}, ValidateProcessor)
+			if err != nil {
+				t.Fatalf("ProcessFileWithOptions() error = %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("ProcessFileWithOptions() returned %d results, want %d", len(results), tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessFileSkipHeader(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.txt")
+	if err := os.WriteFile(filename, []byte("\ncpf\n529.982.247-25\n111.444.777-35\n11144477734\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts ProcessOptions
+		want []string
+	}{
+		{"header", ProcessOptions{SkipHeader: true}, []string{"529.982.247-25", "111.444.777-35", "11144477734"}},
+		{"header and limit", ProcessOptions{SkipHeader: true, Limit: 2}, []string{"529.982.247-25", "111.444.777-35"}},
+		{"no header", ProcessOptions{}, []string{"cpf", "529.982.247-25", "111.444.777-35", "11144477734"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ProcessFileWithOptions(filename, tt.opts, ValidateProcessor)
+			if err != nil {
+				t.Fatalf("ProcessFileWithOptions() error = %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.CPF)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProcessFileWithOptions() CPFs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessFileLongLines(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.bin")
+	// The second line is longer than bufio.Scanner reads by default, the third
+	// starts with a valid CPF, and the fourth is longer than the scanner can
+	// read at all
+	content := "529.982.247-25\n" + strings.Repeat("\x00\xff1", 40000) + "\n" + "529.982.247-25" + strings.Repeat("x", 60) + "\n" +
+		strings.Repeat("7", 3*maxScanLine+5) + "\n111.444.777-35\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ProcessFile(filename, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFile() error = %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("ProcessFile() returned %d results, want 5", len(results))
+	}
+	if !results[0].Valid || !results[4].Valid || results[4].Line != 5 {
+		t.Errorf("results around the long lines = %+v, %+v, want valid", results[0], results[4])
+	}
+	for _, r := range results[1:4] {
+		if r.Valid || r.Reason != "length" || !strings.Contains(r.Error, "line too long") || len(r.CPF) > 70 {
+			t.Errorf("long line result = %+v, want a malformed result with a short CPF", r)
+		}
+	}
+	if want := fmt.Sprintf("(%d bytes", 3*maxScanLine+5); !strings.Contains(results[3].Error, want) {
+		t.Errorf("results[3].Error = %q, want the full line size", results[3].Error)
+	}
+	if s := Summarize(results); s.Malformed != 3 {
+		t.Errorf("Summarize() = %+v, want 3 malformed", s)
+	}
+
+	// Long lines aren't processed, whatever the processor
+	results, err = ProcessFile(filename, FormatProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFile(FormatProcessor) error = %v", err)
+	}
+	if r := results[2]; r.Valid || r.Reason != "length" || !strings.Contains(r.Error, "line too long") {
+		t.Errorf("formatted long line = %+v, want a malformed line too long", r)
+	}
+
+	// Readers that don't process lines get them whole, up to maxScanLine
+	lines, err := ReadLines(filename, ProcessOptions{})
+	if err != nil {
+		t.Fatalf("ReadLines() error = %v", err)
+	}
+	if len(lines) != 5 || len(lines[1]) != 120000 || len(lines[3]) != maxScanLine {
+		t.Fatalf("ReadLines() = %d lines, long ones of %d and %d bytes", len(lines), len(lines[1]), len(lines[3]))
+	}
+
+	// ValidateAll reports the same lines as malformed
+	for i, r := range ValidateAll(lines, 2) {
+		if i >= 1 && i <= 3 && (r.Valid || r.Reason != "length" || !strings.Contains(r.Error, "line too long")) {
+			t.Errorf("ValidateAll()[%d] = %+v, want a malformed line too long", i, r)
+		}
+	}
+}
+
+func TestProcessFileLineNumbers(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.txt")
+	content := "\n529.982.247-25\n\n   \n123\n11144477735\n\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ProcessFile(filename, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFile() error = %v", err)
+	}
+	want := map[string]int{"529.982.247-25": 2, "123": 5, "11144477735": 6}
+	if len(results) != len(want) {
+		t.Fatalf("ProcessFile() returned %d results, want %d", len(results), len(want))
+	}
+	for _, r := range results {
+		if r.Line != want[r.CPF] {
+			t.Errorf("%s: Line = %d, want %d", r.CPF, r.Line, want[r.CPF])
+		}
+	}
+}
+
+func TestProcessFileUntilInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.txt")
+	content := "529.982.247-25\n\n11144477735\n123\n111.444.777-35\n456\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var processed []string
+	results, err := ProcessFileUntilInvalid(filename, ProcessOptions{}, func(cpf string) CPFResult {
+		processed = append(processed, cpf)
+		return ValidateProcessor(cpf)
+	})
+	if err != nil {
+		t.Fatalf("ProcessFileUntilInvalid() error = %v", err)
+	}
+	if want := []string{"529.982.247-25", "11144477735", "123"}; !reflect.DeepEqual(processed, want) {
+		t.Errorf("processed %v, want %v", processed, want)
+	}
+	if len(results) != 3 {
+		t.Fatalf("ProcessFileUntilInvalid() returned %d results, want 3", len(results))
+	}
+	if last := results[2]; last.Valid || last.CPF != "123" || last.Line != 4 {
+		t.Errorf("last result = %+v, want invalid 123 on line 4", last)
+	}
+}
+
+func TestProcessFileMaxErrors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.txt")
+	content := "529.982.247-25\n1\n2\n11144477735\n3\n4\n5\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var processed int
+	results, err := ProcessFileWithOptions(filename, ProcessOptions{MaxErrors: 3}, func(cpf string) CPFResult {
+		processed++
+		return ValidateProcessor(cpf)
+	})
+	if !errors.Is(err, ErrMaxErrors) {
+		t.Fatalf("ProcessFileWithOptions() error = %v, want ErrMaxErrors", err)
+	}
+	if processed != 5 || len(results) != 5 {
+		t.Errorf("processed %d lines and returned %d results, want 5 before stopping", processed, len(results))
+	}
+	if last := results[len(results)-1]; last.CPF != "3" {
+		t.Errorf("last result = %+v, want the third invalid CPF", last)
+	}
+
+	results, err = ProcessFileWithOptions(filename, ProcessOptions{MaxErrors: 6}, ValidateProcessor)
+	if err != nil || len(results) != 7 {
+		t.Errorf("ProcessFileWithOptions(MaxErrors: 6) = %d results, %v; want all 7", len(results), err)
+	}
+}
+
+func TestProcessFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("529.982.247-25\n123\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("\n11144477735\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ProcessFiles([]string{first, second}, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFiles() error = %v", err)
+	}
+	want := []struct {
+		cpf    string
+		source string
+		line   int
+	}{
+		{"529.982.247-25", first, 1},
+		{"123", first, 2},
+		{"11144477735", second, 2},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("ProcessFiles() returned %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if r := results[i]; r.CPF != w.cpf || r.Source != w.source || r.Line != w.line {
+			t.Errorf("results[%d] = %+v, want %s from %s:%d", i, r, w.cpf, w.source, w.line)
+		}
+	}
+
+	if _, err := ProcessFiles([]string{first, filepath.Join(dir, "missing.txt")}, ValidateProcessor); err == nil {
+		t.Error("ProcessFiles() with a missing file expected error")
+	}
+}
+
+func TestProcessFileDelimited(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.csv")
+	if err := os.WriteFile(filename, []byte("529.982.247-25, 123 ,,11144477735\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ProcessFileDelimited(filename, ",", ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFileDelimited() error = %v", err)
+	}
+
+	want := []struct {
+		cpf   string
+		valid bool
+	}{
+		{"529.982.247-25", true},
+		{"123", false},
+		{"11144477735", true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		if results[i].CPF != w.cpf || results[i].Valid != w.valid {
+			t.Errorf("results[%d] = %+v, want %s valid=%v", i, results[i], w.cpf, w.valid)
+		}
+	}
+}
+
+func TestProcessFileGzip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.txt.gz")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	if _, err := gz.Write([]byte("529.982.247-25\n\n11144477734\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ProcessFile(filename, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFile() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ProcessFile() returned %d results, want 2", len(results))
+	}
+	if results[0].CPF != "529.982.247-25" || !results[0].Valid {
+		t.Errorf("results[0] = %+v, want valid 529.982.247-25", results[0])
+	}
+	if results[1].CPF != "11144477734" || results[1].Valid {
+		t.Errorf("results[1] = %+v, want invalid 11144477734", results[1])
+	}
+}
+
+func TestProcessFileLatin1(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cpfs.txt")
+	// "José" and "São Paulo" in ISO-8859-1, where é is 0xE9 and ã is 0xE3
+	if err := os.WriteFile(filename, []byte("529.982.247-25 Jos\xe9\nS\xe3o Paulo 11144477735\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ProcessFileWithOptions(filename, ProcessOptions{Encoding: "latin1"}, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFileWithOptions() error = %v", err)
+	}
+	want := []string{"529.982.247-25 José", "São Paulo 11144477735"}
+	if len(results) != len(want) {
+		t.Fatalf("ProcessFileWithOptions() returned %d results, want %d", len(results), len(want))
+	}
+	for i, r := range results {
+		if r.Original != want[i] || !r.Valid {
+			t.Errorf("results[%d] = %+v, want valid with original %q", i, r, want[i])
+		}
+	}
+
+	if _, err := ProcessFileWithOptions(filename, ProcessOptions{Encoding: "ebcdic"}, ValidateProcessor); err == nil {
+		t.Error("ProcessFileWithOptions() with unknown encoding error = nil, want error")
+	}
+}
+
+func TestProcessFilePlainShortFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "one.txt")
+	if err := os.WriteFile(filename, []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := ProcessFile(filename, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessFile() error = %v", err)
+	}
+	if len(results) != 1 || results[0].CPF != "1" {
+		t.Errorf("ProcessFile() = %+v, want a single result for \"1\"", results)
+	}
+}
+
+func TestNormalizeProcessor(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantCPF    string
+		wantDigits string
+		wantError  bool
+	}{
+		{"well-formed", "529.982.247-25", "529.982.247-25", "52998224725", false},
+		{"digits only", "52998224725", "529.982.247-25", "52998224725", false},
+		{"dirty", " 529 982.247/25x ", "529.982.247-25", "52998224725", false},
+		{"too short", "529.982-24", "529.982-24", "529.982-24", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeProcessor(tt.input)
+			if got.CPF != tt.wantCPF || (got.Error != "") != tt.wantError || got.Original != tt.input {
+				t.Errorf("NormalizeProcessor() = %+v, want CPF %q (error %v)", got, tt.wantCPF, tt.wantError)
+			}
+			got = NormalizeDigitsProcessor(tt.input)
+			if got.CPF != tt.wantDigits || (got.Error != "") != tt.wantError {
+				t.Errorf("NormalizeDigitsProcessor() = %+v, want CPF %q (error %v)", got, tt.wantDigits, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestProcessFileContextCancel(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "large.txt")
+	content := strings.Repeat("529.982.247-25\n", 10000)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := 0
+	results, err := ProcessFileContext(ctx, filename, func(line string) CPFResult {
+		processed++
+		if processed == 100 {
+			cancel()
+		}
+		return ValidateProcessor(line)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ProcessFileContext() error = %v, want context.Canceled", err)
+	}
+	if results != nil {
+		t.Errorf("ProcessFileContext() returned %d results, want none", len(results))
+	}
+	if processed != 100 {
+		t.Errorf("processed %d lines after cancellation, want 100", processed)
+	}
+}
+
+func TestWriteJSONStreamTo(t *testing.T) {
+	for _, n := range []int{0, 1, 50} {
+		want := make([]CPFResult, 0, n)
+		for i := 0; i < n; i++ {
+			want = append(want, CPFResult{CPF: strconv.Itoa(i), Valid: i%2 == 0})
+		}
+
+		ch := make(chan CPFResult)
+		go func() {
+			for _, r := range want {
+				ch <- r
+			}
+			close(ch)
+		}()
+
+		var streamed bytes.Buffer
+		if err := WriteJSONStreamTo(&streamed, ch); err != nil {
+			t.Fatalf("WriteJSONStreamTo() error = %v", err)
+		}
+
+		var got []CPFResult
+		if err := json.Unmarshal(streamed.Bytes(), &got); err != nil {
+			t.Fatalf("output is not valid JSON: %v\n%s", err, streamed.String())
+		}
+		if len(got) != n {
+			t.Fatalf("decoded %d results, want %d", len(got), n)
+		}
+		for i := range want {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Errorf("result[%d] = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+
+		var buffered bytes.Buffer
+		if err := WriteJSON(&buffered, want); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+		if streamed.String() != buffered.String() {
+			t.Errorf("streamed output differs from WriteJSON:\n%s\nvs\n%s", streamed.String(), buffered.String())
+		}
+	}
+}
+
+func TestWriteJSONCompact(t *testing.T) {
+	results := []CPFResult{
+		{CPF: "529.982.247-25", Valid: true, Original: "52998224725"},
+		{CPF: "123", Reason: "length", Original: "123"},
+		{CPF: "111.444.777-35", Valid: true, Original: "111.444.777-35"},
+	}
+
+	var indented, compact bytes.Buffer
+	if err := WriteJSON(&indented, results); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if err := WriteJSONCompact(&compact, results); err != nil {
+		t.Fatalf("WriteJSONCompact() error = %v", err)
+	}
+
+	if compact.Len() >= indented.Len() {
+		t.Errorf("compact output has %d bytes, want fewer than the %d of indented output", compact.Len(), indented.Len())
+	}
+	if n := strings.Count(compact.String(), "\n"); n != 1 {
+		t.Errorf("compact output has %d lines, want 1:\n%s", n, compact.String())
+	}
+
+	var got []CPFResult
+	if err := json.Unmarshal(compact.Bytes(), &got); err != nil {
+		t.Fatalf("compact output is not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, results) {
+		t.Errorf("compact output decoded to %+v, want %+v", got, results)
+	}
+
+	var streamed bytes.Buffer
+	if err := WriteJSONCompactStreamTo(&streamed, sliceChan(results)); err != nil {
+		t.Fatalf("WriteJSONCompactStreamTo() error = %v", err)
+	}
+	if streamed.String() != compact.String() {
+		t.Errorf("streamed output differs from WriteJSONCompact:\n%s\nvs\n%s", streamed.String(), compact.String())
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	results := []CPFResult{
+		{CPF: "529.982.247-25", Valid: true},
+		{CPF: "123", Reason: "length"},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderTemplate(results, "{{.CPF}} -> {{.Valid}}{{with .Reason}} ({{.}}){{end}}", &buf); err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	want := "529.982.247-25 -> true\n123 -> false (length)\n"
+	if buf.String() != want {
+		t.Errorf("RenderTemplate() = %q, want %q", buf.String(), want)
+	}
+
+	for _, tmpl := range []string{"{{.CPF", "{{.Missing}}"} {
+		if err := RenderTemplate(results, tmpl, io.Discard); err == nil {
+			t.Errorf("RenderTemplate(%q) expected error", tmpl)
+		}
+	}
+}
+
+func TestGenerateCPFsMap(t *testing.T) {
+	const count = 12
+	m, err := GenerateCPFsMap(count, true, false)
+	if err != nil {
+		t.Fatalf("GenerateCPFsMap() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	cpfs := make([]string, count)
+	for i := range cpfs {
+		cpfs[i] = m[strconv.Itoa(i)]
+	}
+	if err := WriteJSONObject(&buf, cpfs); err != nil {
+		t.Fatalf("WriteJSONObject() error = %v", err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON object: %v\n%s", err, buf.String())
+	}
+	if len(got) != count {
+		t.Fatalf("decoded %d keys, want %d", len(got), count)
+	}
+	for i := 0; i < count; i++ {
+		cpf, ok := got[strconv.Itoa(i)]
+		if !ok {
+			t.Fatalf("key %q missing from %v", strconv.Itoa(i), got)
+		}
+		if !ValidateCPF(cpf, true) {
+			t.Errorf("got[%d] = %q, not a valid formatted CPF", i, cpf)
+		}
+	}
+	if strings.Index(buf.String(), `"2"`) > strings.Index(buf.String(), `"10"`) {
+		t.Errorf("keys aren't in numeric order:\n%s", buf.String())
+	}
+}
+
+func TestPartitionResults(t *testing.T) {
+	results := ValidateAll([]string{"52998224725", "123", "52998224724", "abc", "111.444.777-35", "11111111111"}, 1)
+	valid, invalid, malformed := PartitionResults(results)
+
+	cpfs := func(results []CPFResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.CPF)
+		}
+		return out
+	}
+	if got, want := cpfs(valid), []string{"52998224725", "111.444.777-35"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("valid = %v, want %v", got, want)
+	}
+	if got, want := cpfs(invalid), []string{"52998224724", "11111111111"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid = %v, want %v", got, want)
+	}
+	if got, want := cpfs(malformed), []string{"123", "abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("malformed = %v, want %v", got, want)
+	}
+}
+
+func TestWriteJSONEnvelope(t *testing.T) {
+	results := []CPFResult{
+		{CPF: "529.982.247-25", Valid: true},
+		{CPF: "123", Reason: "length"},
+	}
+	filename := filepath.Join(t.TempDir(), "out.json")
+	before := time.Now().Add(-time.Second)
+	if err := WriteJSONEnvelope(results, "1.2.3", filename); err != nil {
+		t.Fatalf("WriteJSONEnvelope() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("output is not a JSON object: %v\n%s", err, data)
+	}
+	for _, key := range []string{"version", "generated_at", "count", "results"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("envelope is missing %q:\n%s", key, data)
+		}
+	}
+
+	var got Envelope
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "1.2.3" || got.Count != len(results) || !reflect.DeepEqual(got.Results, results) {
+		t.Errorf("envelope = %+v", got)
+	}
+	if got.GeneratedAt.Before(before) || got.GeneratedAt.After(time.Now()) {
+		t.Errorf("generated_at = %v, want about now", got.GeneratedAt)
+	}
+
+	var empty bytes.Buffer
+	if err := WriteJSONEnvelopeTo(&empty, nil, "dev"); err != nil || !strings.Contains(empty.String(), `"results": []`) {
+		t.Errorf("WriteJSONEnvelopeTo(nil) = %q (error %v), want empty results", empty.String(), err)
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	input := []string{"529.982.247-25", "11144477735", "abc", "123", "111.444.777-35"}
+	cpfs := func(results []CPFResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.CPF)
+		}
+		return out
+	}
+
+	results := ValidateAll(input, 1)
+	SortResults(results, false)
+	if got, want := cpfs(results), []string{"abc", "11144477735", "111.444.777-35", "123", "529.982.247-25"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ascending = %v, want %v", got, want)
+	}
+
+	results = ValidateAll(input, 1)
+	SortResults(results, true)
+	if got, want := cpfs(results), []string{"529.982.247-25", "123", "11144477735", "111.444.777-35", "abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("descending = %v, want %v", got, want)
+	}
+}
+
+func TestWriteJSONLOutput(t *testing.T) {
+	results := []CPFResult{
+		{CPF: "52998224725", Valid: true, Original: "52998224725"},
+		{CPF: "123", Reason: "length", Original: "123"},
+	}
+	filename := filepath.Join(t.TempDir(), "out.jsonl")
+	if err := WriteJSONLOutput(results, filename); err != nil {
+		t.Fatalf("WriteJSONLOutput() error = %v", err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	var got []CPFResult
+	for scanner.Scan() {
+		var r CPFResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		got = append(got, r)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, results) {
+		t.Errorf("read back %+v, want %+v", got, results)
+	}
+
+	var empty bytes.Buffer
+	if err := WriteJSONL(&empty, nil); err != nil || empty.Len() != 0 {
+		t.Errorf("WriteJSONL(nil) = %q (error %v), want no output", empty.String(), err)
+	}
+}
+
+func TestWriteXMLOutput(t *testing.T) {
+	results := []CPFResult{
+		{CPF: "52998224725", Valid: true, Original: "52998224725"},
+		{CPF: "123", Reason: "length", Original: "123"},
+		{CPF: "abc", Error: "invalid CPF number (must have 11 digits)", Original: "abc"},
+	}
+	filename := filepath.Join(t.TempDir(), "out.xml")
+	if err := WriteXMLOutput(results, filename); err != nil {
+		t.Fatalf("WriteXMLOutput() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name    `xml:"cpfs"`
+		Results []CPFResult `xml:"cpf"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(doc.Results, results) {
+		t.Errorf("read back %+v, want %+v", doc.Results, results)
+	}
+}
@@ -1,26 +1,20 @@
 package cpf
 
 import (
-	"crypto/rand"
 	"fmt"
-	"math/big"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-func cryptoRandInt(max int) (int, error) {
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
-	if err != nil {
-		return 0, err
-	}
-	return int(n.Int64()), nil
-}
-
 // UnformatCPF removes all non-digit characters from the input string.
 func UnformatCPF(cpfStr string) string {
-	re := regexp.MustCompile(`\D`)
-	return re.ReplaceAllString(cpfStr, "")
+	digits := make([]byte, 0, len(cpfStr))
+	for i := 0; i < len(cpfStr); i++ {
+		if c := cpfStr[i]; c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	return string(digits)
 }
 
 // IsRepeated checks if the string is composed entirely of the same character.
@@ -39,113 +33,345 @@ func IsRepeated(s string) bool {
 
 // FormatCPF formats an 11-digit CPF string as ###.###.###-##.
 func FormatCPF(cpfStr string) (string, error) {
+	return FormatCPFCustom(cpfStr, ".", "-")
+}
+
+// FormatCPFCustom formats an 11-digit CPF string like FormatCPF, but with
+// groupSep between the groups of three digits and dvSep before the check
+// digits, e.g. "529 982 247 25" or "529/982/247/25".
+func FormatCPFCustom(cpfStr, groupSep, dvSep string) (string, error) {
 	digits := UnformatCPF(cpfStr)
 	if len(digits) != 11 {
-		return "", fmt.Errorf("invalid CPF number (must have 11 digits)")
+		return "", lengthError(len(digits))
 	}
-	return fmt.Sprintf("%s.%s.%s-%s",
-		digits[0:3],
-		digits[3:6],
-		digits[6:9],
-		digits[9:11],
-	), nil
+	return digits[0:3] + groupSep +
+		digits[3:6] + groupSep +
+		digits[6:9] + dvSep +
+		digits[9:11], nil
 }
 
-// calc implements the internal sum with the CPF weighting logic.
-func calc(nums []int) int {
-	total := 0
-	for i, num := range nums {
-		total += num * (9 - (i % 10))
+// lengthError reports a CPF with n digits instead of 11, saying how far off
+// it is, e.g. "got 10 digits, need 11 (1 short)".
+func lengthError(n int) error {
+	noun := "digits"
+	if n == 1 {
+		noun = "digit"
 	}
-	return total
+	if n < 11 {
+		return reasonErrorf(ErrLength, "invalid CPF number: got %d %s, need 11 (%d short)", n, noun, 11-n)
+	}
+	return reasonErrorf(ErrLength, "invalid CPF number: got %d %s, need 11 (%d too many)", n, noun, n-11)
 }
 
-// getCD computes the 2 check digits (DV) from the first 9 digits of a CPF.
-func getCD(digits9 []int) ([2]int, error) {
-	if len(digits9) != 9 {
-		return [2]int{}, fmt.Errorf("invalid digits length: expected 9, got %d", len(digits9))
+// FormatPartialCPF formats the digits of a possibly incomplete CPF as far as
+// they go, e.g. "52998" becomes "529.98", for masking input as it is typed.
+// Non-digit characters are ignored and digits beyond the 11th are dropped.
+func FormatPartialCPF(cpfStr string) string {
+	digits := UnformatCPF(cpfStr)
+	if len(digits) > 11 {
+		digits = digits[:11]
 	}
 
-	reversed := make([]int, 9)
-	for i := 0; i < 9; i++ {
-		reversed[i] = digits9[9-1-i]
+	var b strings.Builder
+	for i := 0; i < len(digits); i++ {
+		switch i {
+		case 3, 6:
+			b.WriteByte('.')
+		case 9:
+			b.WriteByte('-')
+		}
+		b.WriteByte(digits[i])
 	}
+	return b.String()
+}
 
-	cd1 := calc(reversed) % 11 % 10
-
-	secondInput := append([]int{0}, reversed...)
-	cd2 := (calc(secondInput) + cd1*9) % 11 % 10
+// ToDigits returns an 11-digit CPF string as digits only, the counterpart to
+// FormatCPF.
+func ToDigits(cpfStr string) (string, error) {
+	digits := UnformatCPF(cpfStr)
+	if len(digits) != 11 {
+		return "", lengthError(len(digits))
+	}
+	return digits, nil
+}
 
-	return [2]int{cd1, cd2}, nil
+// CPFEqual reports whether a and b hold the same digits, ignoring formatting,
+// so "529.982.247-25" equals "52998224725". The CPFs need not be valid, but
+// input without any digits is never equal to anything.
+func CPFEqual(a, b string) bool {
+	digitsA, digitsB := UnformatCPF(a), UnformatCPF(b)
+	return digitsA != "" && digitsA == digitsB
 }
 
-// ValidateCPF checks if the provided CPF string is valid.
-func ValidateCPF(cpfStr string, byLength bool) bool {
-	unformatted := UnformatCPF(cpfStr)
-	if len(unformatted) != 11 {
-		return false
+var (
+	// firstWeights are applied to the first 9 digits to compute the first DV.
+	firstWeights = []int{10, 9, 8, 7, 6, 5, 4, 3, 2}
+	// secondWeights are applied to the first 10 digits to compute the second DV.
+	secondWeights = []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}
+)
+
+// Mod11CheckDigit computes a single mod-11 check digit, as used by CPF, CNPJ
+// and other Brazilian documents: the digits are multiplied by the matching
+// weights and summed, and a remainder r of the sum by 11 yields 11 - r, or 0
+// when r is below 2. weights must be at least as long as digits.
+func Mod11CheckDigit(digits []int, weights []int) int {
+	total := 0
+	for i, d := range digits {
+		total += d * weights[i]
 	}
-	if IsRepeated(unformatted) {
-		return false
+	rest := total % 11
+	if rest < 2 {
+		return 0
 	}
+	return 11 - rest
+}
 
-	if byLength {
-		return true
+// getCD computes the 2 check digits (DV) from the first 9 digits of a CPF.
+func getCD(digits9 []int) ([2]int, error) {
+	if len(digits9) != 9 {
+		return [2]int{}, reasonErrorf(ErrLength, "invalid digits length: expected 9, got %d", len(digits9))
 	}
 
-	number9 := unformatted[:9]
-	dv2 := unformatted[9:11]
+	cd1 := Mod11CheckDigit(digits9, firstWeights)
+	cd2 := Mod11CheckDigit(append(append([]int{}, digits9...), cd1), secondWeights)
 
-	var digits9 []int
-	for _, ch := range number9 {
-		d, err := strconv.Atoi(string(ch))
-		if err != nil {
-			return false
-		}
-		digits9 = append(digits9, d)
+	return [2]int{cd1, cd2}, nil
+}
+
+// CalculateCheckDigits returns the two check digits (DV) for the first nine
+// digits of a CPF. The input may be formatted (e.g. "111.444.777"), but any
+// character other than digits, dots, dashes and spaces is rejected.
+func CalculateCheckDigits(first9 string) (string, error) {
+	digits9, err := parseDigits(first9)
+	if err != nil {
+		return "", err
+	}
+	if len(digits9) != 9 {
+		return "", reasonErrorf(ErrLength, "invalid CPF base (must have 9 digits, got %d)", len(digits9))
 	}
 
 	cd, err := getCD(digits9)
 	if err != nil {
-		return false
+		return "", err
 	}
-	trueDV := fmt.Sprintf("%d%d", cd[0], cd[1])
+	return fmt.Sprintf("%d%d", cd[0], cd[1]), nil
+}
 
-	return dv2 == trueDV
+// CompleteCPF appends the check digits to the first nine digits of a CPF,
+// returning the full CPF. Any non-digit character of first9 is ignored, but
+// exactly nine digits must remain.
+func CompleteCPF(first9 string, formatted bool) (string, error) {
+	digits := UnformatCPF(first9)
+	if len(digits) != 9 {
+		return "", reasonErrorf(ErrLength, "invalid CPF base (must have 9 digits, got %d)", len(digits))
+	}
+	dv, err := CalculateCheckDigits(digits)
+	if err != nil {
+		return "", err
+	}
+	if formatted {
+		return FormatCPF(digits + dv)
+	}
+	return digits + dv, nil
 }
 
-// GenerateCPF creates a random CPF number.
-func GenerateCPF(formatted, invalid bool) (string, error) {
-	digits9 := make([]int, 9)
-	for i := 0; i < 9; i++ {
-		digit, err := cryptoRandInt(10)
+// maxBase is the largest value of the first nine digits of a CPF.
+const maxBase = 999999999
+
+// GenerateRange returns the CPF for each value of the first nine digits from
+// from to to, inclusive, in order. Values made of a single repeated digit,
+// such as 111111111, are skipped, as their CPFs are never valid. Wide ranges
+// are better consumed with GenerateRangeStream, which doesn't hold them in
+// memory.
+func GenerateRange(from, to int, formatted bool) ([]string, error) {
+	var cpfs []string
+	for cpf, err := range GenerateRangeStream(from, to, formatted) {
 		if err != nil {
-			return "", fmt.Errorf("failed to generate random digit: %w", err)
+			return nil, err
 		}
-		digits9[i] = digit
+		cpfs = append(cpfs, cpf)
 	}
+	return cpfs, nil
+}
 
-	var dv [2]int
-	if invalid {
-		d1, err := cryptoRandInt(10)
-		if err != nil {
-			return "", fmt.Errorf("failed to generate random digit: %w", err)
-		}
-		d2, err := cryptoRandInt(10)
-		if err != nil {
-			return "", fmt.Errorf("failed to generate random digit: %w", err)
+// checkRange validates the bounds of a range of CPF bases.
+func checkRange(from, to int) error {
+	if from < 0 || to > maxBase {
+		return fmt.Errorf("invalid range %d to %d (must be between 0 and %d)", from, to, maxBase)
+	}
+	if from > to {
+		return fmt.Errorf("invalid range %d to %d (from must not be greater than to)", from, to)
+	}
+	return nil
+}
+
+// parseDigits converts the digits of s to ints, ignoring dots, dashes and
+// spaces. Any other non-digit character is rejected.
+func parseDigits(s string) ([]int, error) {
+	digits := make([]int, 0, len(s))
+	for _, ch := range s {
+		switch {
+		case ch == '.' || ch == '-' || ch == ' ':
+			continue
+		case ch < '0' || ch > '9':
+			return nil, reasonErrorf(ErrNonNumeric, "invalid character %q in CPF base", ch)
 		}
-		dv[0] = d1
-		dv[1] = d2
-	} else {
-		correctDV, err := getCD(digits9)
-		if err != nil {
-			return "", fmt.Errorf("failed to generate check digits: %w", err)
+		digits = append(digits, int(ch-'0'))
+	}
+	return digits, nil
+}
+
+// CorrectCheckDigits returns the check digits expected for a CPF alongside
+// the ones it actually carries, which helps spot typos in the DV.
+func CorrectCheckDigits(cpfStr string) (expected string, given string, err error) {
+	digits := UnformatCPF(cpfStr)
+	if len(digits) != 11 {
+		return "", "", lengthError(len(digits))
+	}
+	if IsRepeated(digits) {
+		return "", "", reasonErrorf(ErrRepeated, "invalid CPF number (repeated digits)")
+	}
+
+	expected, err = CalculateCheckDigits(digits[:9])
+	if err != nil {
+		return "", "", err
+	}
+	return expected, digits[9:], nil
+}
+
+// ValidationError describes why a CPF failed validation. It is also an
+// error, so that the errors returned by this package can be matched against
+// a reason with errors.Is, e.g. errors.Is(err, ErrLength).
+type ValidationError int
+
+const (
+	// ErrNone means the CPF passed validation.
+	ErrNone ValidationError = iota
+	// ErrLength means the CPF does not have exactly 11 digits.
+	ErrLength
+	// ErrRepeated means the CPF is composed of a single repeated digit.
+	ErrRepeated
+	// ErrCheckDigit means the check digits (DV) do not match the first 9 digits.
+	ErrCheckDigit
+	// ErrNonNumeric means the input does not contain any digits at all, or
+	// holds a character other than a digit where only digits are allowed.
+	ErrNonNumeric
+	// ErrBlocklisted means the CPF passes the check digit algorithm but is a
+	// well-known sample number rejected by the blocklist.
+	ErrBlocklisted
+	// ErrRegion means the CPF is valid but was issued by a fiscal region
+	// other than the expected one.
+	ErrRegion
+)
+
+// ErrInvalidLength is ErrLength under the name errors.Is callers may expect.
+const ErrInvalidLength = ErrLength
+
+// String returns a short machine-friendly name for the validation error.
+func (e ValidationError) String() string {
+	switch e {
+	case ErrNone:
+		return "none"
+	case ErrLength:
+		return "length"
+	case ErrRepeated:
+		return "repeated"
+	case ErrCheckDigit:
+		return "check_digit"
+	case ErrNonNumeric:
+		return "non_numeric"
+	case ErrBlocklisted:
+		return "blocklisted"
+	case ErrRegion:
+		return "region"
+	default:
+		return fmt.Sprintf("ValidationError(%d)", int(e))
+	}
+}
+
+// Error returns the same short name as String.
+func (e ValidationError) Error() string {
+	return e.String()
+}
+
+// reasonError is an error whose message is independent of the
+// ValidationError it wraps, which keeps messages unchanged while letting
+// callers use errors.Is.
+type reasonError struct {
+	msg    string
+	reason ValidationError
+}
+
+func (e *reasonError) Error() string { return e.msg }
+
+func (e *reasonError) Unwrap() error { return e.reason }
+
+// reasonErrorf formats an error message that matches reason with errors.Is.
+func reasonErrorf(reason ValidationError, format string, args ...any) error {
+	return &reasonError{msg: fmt.Sprintf(format, args...), reason: reason}
+}
+
+// ValidateCPFDetailed checks if the provided CPF string is valid and reports
+// the reason when it is not.
+func ValidateCPFDetailed(cpfStr string) (bool, ValidationError) {
+	v := NewValidator()
+	return v.ValidateDetailed(cpfStr)
+}
+
+// ValidateCPF checks if the provided CPF string is valid. It is lenient about
+// formatting: every non-digit character, including whitespace anywhere in
+// the input, is ignored, so "529 982\t247/25" is as valid as "52998224725".
+// Use ValidateStrict to reject such input.
+func ValidateCPF(cpfStr string, byLength bool) bool {
+	v := NewValidator(WithLengthOnly(byLength))
+	return v.Validate(cpfStr)
+}
+
+// ValidateStrict is like ValidateCPF but only accepts digits and the "." and
+// "-" separators of the formatted CPF. Leading and trailing whitespace is
+// ignored; any other character makes the CPF invalid.
+func ValidateStrict(cpfStr string) bool {
+	for _, c := range strings.TrimSpace(cpfStr) {
+		if (c < '0' || c > '9') && c != '.' && c != '-' {
+			return false
 		}
-		dv = correctDV
 	}
+	return ValidateCPF(cpfStr, false)
+}
+
+// GenerateCPFExcludingRegions creates a random valid CPF issued by any fiscal
+// region but those in exclude.
+func GenerateCPFExcludingRegions(exclude []int, formatted bool) (string, error) {
+	return defaultGenerator.GenerateExcludingRegions(exclude, formatted)
+}
+
+// GenerateCPFWithPrefix creates a random valid CPF starting with prefix,
+// which holds up to 9 digits.
+func GenerateCPFWithPrefix(prefix string, formatted bool) (string, error) {
+	return defaultGenerator.GenerateWithPrefix(prefix, formatted)
+}
+
+// GenerateCPFWithPattern creates a random valid CPF whose first 9 digits
+// follow pattern, where '#' and '?' stand for random digits.
+func GenerateCPFWithPattern(pattern string, formatted bool) (string, error) {
+	return defaultGenerator.GenerateWithPattern(pattern, formatted)
+}
 
-	allDigits := append(digits9, dv[0], dv[1])
+// GenerateCPF creates a random CPF number. With invalid set, the check digits
+// are guaranteed to be wrong.
+func GenerateCPF(formatted, invalid bool) (string, error) {
+	return defaultGenerator.Generate(formatted, invalid)
+}
+
+// GenerateCPFForRegion creates a random valid CPF whose 9th digit matches the
+// given fiscal region code.
+func GenerateCPFForRegion(region int, formatted bool) (string, error) {
+	return defaultGenerator.GenerateForRegion(region, formatted)
+}
+
+// buildCPF joins the first 9 digits and the check digits into a CPF string.
+func buildCPF(digits9 []int, dv [2]int, formatted bool) (string, error) {
+	allDigits := append(append([]int{}, digits9...), dv[0], dv[1])
 	cpfStr := strings.Builder{}
 	for _, d := range allDigits {
 		cpfStr.WriteString(strconv.Itoa(d))
@@ -159,4 +385,4 @@ func GenerateCPF(formatted, invalid bool) (string, error) {
 		return result, nil
 	}
 	return cpfStr.String(), nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,121 @@
+package cpf
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVRecord is a row of a CSV file: the CPF read from the selected column and
+// the values of the other columns, keyed by their header.
+type CSVRecord struct {
+	CPF    string
+	Fields map[string]string
+}
+
+// ReadCSVColumn reads the CPFs in a column of a CSV file with a header row.
+// column is either a header name or a 1-based column index.
+func ReadCSVColumn(filename, column string) ([]string, error) {
+	records, err := ReadCSV(filename, column)
+	if err != nil {
+		return nil, err
+	}
+
+	cpfs := make([]string, 0, len(records))
+	for _, record := range records {
+		cpfs = append(cpfs, record.CPF)
+	}
+	return cpfs, nil
+}
+
+// ReadCSV reads the rows of a CSV file with a header row, taking the CPF from
+// column, which is either a header name or a 1-based column index. Rows with
+// an empty CPF are skipped.
+func ReadCSV(filename, column string) ([]CSVRecord, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("CSV file %s is empty", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+
+	index, err := csvColumnIndex(header, column)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []CSVRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		if index >= len(row) || strings.TrimSpace(row[index]) == "" {
+			continue
+		}
+
+		record := CSVRecord{
+			CPF:    strings.TrimSpace(row[index]),
+			Fields: make(map[string]string, len(header)-1),
+		}
+		for i, name := range header {
+			if i != index && i < len(row) {
+				record.Fields[name] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ProcessCSV processes the CPFs in a column of a CSV file using the provided
+// processor function. Each result carries the row's other columns in Fields.
+// Only opts.Limit is honored.
+func ProcessCSV(filename, column string, opts ProcessOptions, processFunc func(string) CPFResult) ([]CPFResult, error) {
+	records, err := ReadCSV(filename, column)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit > 0 && len(records) > opts.Limit {
+		records = records[:opts.Limit]
+	}
+
+	results := make([]CPFResult, 0, len(records))
+	for _, record := range records {
+		result := processFunc(record.CPF)
+		result.Fields = record.Fields
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// csvColumnIndex finds column in header, by name first and then as a 1-based
+// index.
+func csvColumnIndex(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.TrimSpace(name) == column {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(column); err == nil && n >= 1 && n <= len(header) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("column %q not found in CSV header (columns: %s)", column, strings.Join(header, ", "))
+}
@@ -0,0 +1,68 @@
+package cpf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "people.csv")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadCSVColumn(t *testing.T) {
+	filename := writeCSV(t, "name,cpf\nAna,529.982.247-25\n\"Silva, João\",123\nEmpty,\n")
+
+	tests := []struct {
+		column string
+		want   []string
+	}{
+		{"cpf", []string{"529.982.247-25", "123"}},
+		{"2", []string{"529.982.247-25", "123"}},
+		{"name", []string{"Ana", "Silva, João", "Empty"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			got, err := ReadCSVColumn(filename, tt.column)
+			if err != nil {
+				t.Fatalf("ReadCSVColumn() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadCSVColumn() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, column := range []string{"document", "0", "3"} {
+		_, err := ReadCSVColumn(filename, column)
+		if err == nil || !strings.Contains(err.Error(), "not found in CSV header") {
+			t.Errorf("ReadCSVColumn(%q) error = %v, want a missing column error", column, err)
+		}
+	}
+}
+
+func TestProcessCSV(t *testing.T) {
+	filename := writeCSV(t, "name,cpf\nAna,529.982.247-25\nJoão,123\n")
+
+	results, err := ProcessCSV(filename, "cpf", ProcessOptions{}, ValidateProcessor)
+	if err != nil {
+		t.Fatalf("ProcessCSV() error = %v", err)
+	}
+	if len(results) != 2 || !results[0].Valid || results[1].Valid {
+		t.Fatalf("ProcessCSV() = %+v, want [valid, invalid]", results)
+	}
+	if results[0].Fields["name"] != "Ana" || results[1].Fields["name"] != "João" {
+		t.Errorf("ProcessCSV() did not carry the name column through: %+v", results)
+	}
+	if _, ok := results[0].Fields["cpf"]; ok {
+		t.Errorf("Fields should not repeat the CPF column: %+v", results[0].Fields)
+	}
+}
@@ -0,0 +1,30 @@
+package cpf
+
+import "regexp"
+
+// cpfPattern matches CPF-shaped numbers, formatted or not, that are not part
+// of a longer number
+var cpfPattern = regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`)
+
+// ExtractCPFs finds the valid CPFs embedded in free text. Each CPF is returned
+// once, as written in the text, in the order it first appears.
+func ExtractCPFs(text string) []string {
+	return extract(text, false)
+}
+
+// ExtractCandidates is like ExtractCPFs but also returns CPF-shaped numbers
+// whose check digits don't match.
+func ExtractCandidates(text string) []string {
+	return extract(text, true)
+}
+
+func extract(text string, includeInvalid bool) []string {
+	var found []string
+	for _, match := range cpfPattern.FindAllString(text, -1) {
+		if includeInvalid || ValidateCPF(match, false) {
+			found = append(found, match)
+		}
+	}
+	unique, _ := Dedupe(found)
+	return unique
+}
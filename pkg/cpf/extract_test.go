@@ -0,0 +1,29 @@
+package cpf
+
+import (
+	"reflect"
+	"testing"
+)
+
+const extractText = `Prezados, seguem os dados: o cliente João (CPF 529.982.247-25) e
+a cliente Maria, CPF 11144477735, já foram cadastrados. O CPF 123.456.789-00
+informado pelo terceiro não confere. Repetindo: 52998224725. Protocolo
+nº 12345678901234 e telefone 1199999-0000.`
+
+func TestExtractCPFs(t *testing.T) {
+	want := []string{"529.982.247-25", "11144477735"}
+	if got := ExtractCPFs(extractText); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractCPFs() = %q, want %q", got, want)
+	}
+
+	if got := ExtractCPFs("no numbers here"); len(got) != 0 {
+		t.Errorf("ExtractCPFs() = %q, want none", got)
+	}
+}
+
+func TestExtractCandidates(t *testing.T) {
+	want := []string{"529.982.247-25", "11144477735", "123.456.789-00"}
+	if got := ExtractCandidates(extractText); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractCandidates() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,306 @@
+package cpf
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"sync"
+)
+
+// Generator produces random CPF numbers from a configurable source of
+// randomness. The zero value uses crypto/rand. A Generator is safe for
+// concurrent use.
+type Generator struct {
+	mu  sync.Mutex // guards rng, which math/rand doesn't lock
+	rng *mathrand.Rand
+}
+
+// defaultGenerator backs the package-level generation functions.
+var defaultGenerator = NewGenerator(nil)
+
+// NewGenerator creates a Generator drawing digits from src. A nil src uses
+// crypto/rand, which is the right choice unless output must be reproducible.
+func NewGenerator(src mathrand.Source) *Generator {
+	if src == nil {
+		return &Generator{}
+	}
+	return &Generator{rng: mathrand.New(src)}
+}
+
+// NewSeededGenerator creates a deterministic Generator: two generators built
+// with the same seed produce the same sequence of CPFs.
+func NewSeededGenerator(seed int64) *Generator {
+	return NewGenerator(mathrand.NewSource(seed))
+}
+
+// intn returns a random integer in [0, max).
+func (g *Generator) intn(max int) (int, error) {
+	if g.rng != nil {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.rng.Intn(max), nil
+	}
+	return cryptoRandInt(max)
+}
+
+func cryptoRandInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
+
+// randomDigits fills digits with random values between 0 and 9.
+func (g *Generator) randomDigits(digits []int) error {
+	for i := range digits {
+		digit, err := g.intn(10)
+		if err != nil {
+			return fmt.Errorf("failed to generate random digit: %w", err)
+		}
+		digits[i] = digit
+	}
+	return nil
+}
+
+// maxBaseRerolls caps how many times rollBase draws new digits before giving
+// up, which only happens when the fixed digits leave no other choice.
+const maxBaseRerolls = 100
+
+// rollBase calls fill to draw the first 9 digits of a valid CPF into
+// digits9, drawing again while they are a single repeated digit: such CPFs
+// pass the check digit algorithm but are rejected by ValidateCPF.
+func rollBase(digits9 []int, fill func() error) error {
+	for i := 0; i < maxBaseRerolls; i++ {
+		if err := fill(); err != nil {
+			return err
+		}
+		if !repeatedDigits(digits9) {
+			return nil
+		}
+	}
+	return fmt.Errorf("could not generate a CPF without repeated digits")
+}
+
+// repeatedDigits reports whether digits holds a single repeated digit. The
+// check digits of such a base repeat it too, e.g. 111.111.111-11.
+func repeatedDigits(digits []int) bool {
+	for _, d := range digits {
+		if d != digits[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate creates a random CPF number.
+func (g *Generator) Generate(formatted, invalid bool) (string, error) {
+	digits9 := make([]int, 9)
+	fill := func() error { return g.randomDigits(digits9) }
+	if invalid {
+		if err := fill(); err != nil {
+			return "", err
+		}
+	} else if err := rollBase(digits9, fill); err != nil {
+		return "", err
+	}
+
+	correctDV, err := getCD(digits9)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate check digits: %w", err)
+	}
+
+	dv := correctDV
+	if invalid {
+		d := make([]int, 2)
+		if err := g.randomDigits(d); err != nil {
+			return "", err
+		}
+		dv = [2]int{d[0], d[1]}
+		// Random check digits are occasionally right; shift them so the
+		// result is guaranteed to be invalid
+		if dv == correctDV {
+			dv[1] = (dv[1] + 1) % 10
+		}
+	}
+
+	return buildCPF(digits9, dv, formatted)
+}
+
+// GenerateN generates count distinct valid formatted CPFs. It may be called
+// from several goroutines at once.
+func (g *Generator) GenerateN(count int) ([]string, error) {
+	return GenerateUnique(count, func() (string, error) {
+		return g.Generate(true, false)
+	})
+}
+
+// CountBetween returns a random count from min to max, inclusive, such as
+// the size of a batch of fixtures. A seeded generator picks the same count
+// every time.
+func (g *Generator) CountBetween(min, max int) (int, error) {
+	if min <= 0 || min > max {
+		return 0, fmt.Errorf("invalid count range %d to %d (must be positive, with min not greater than max)", min, max)
+	}
+	n, err := g.intn(max - min + 1)
+	if err != nil {
+		return 0, err
+	}
+	return min + n, nil
+}
+
+// GenerateForRegion creates a random valid CPF whose 9th digit matches the
+// given fiscal region code.
+func (g *Generator) GenerateForRegion(region int, formatted bool) (string, error) {
+	if region < 0 || region > 9 {
+		return "", fmt.Errorf("invalid region %d (must be between 0 and 9)", region)
+	}
+
+	digits9 := make([]int, 9)
+	digits9[8] = region
+	if err := rollBase(digits9, func() error { return g.randomDigits(digits9[:8]) }); err != nil {
+		return "", err
+	}
+
+	dv, err := getCD(digits9)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate check digits: %w", err)
+	}
+	return buildCPF(digits9, dv, formatted)
+}
+
+// GenerateExcludingRegions creates a random valid CPF issued by any fiscal
+// region but those in exclude, which may not hold all ten.
+func (g *Generator) GenerateExcludingRegions(exclude []int, formatted bool) (string, error) {
+	excluded := [10]bool{}
+	for _, region := range exclude {
+		if region < 0 || region > 9 {
+			return "", fmt.Errorf("invalid region %d (must be between 0 and 9)", region)
+		}
+		excluded[region] = true
+	}
+	var allowed []int
+	for region, skip := range excluded {
+		if !skip {
+			allowed = append(allowed, region)
+		}
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("cannot exclude every region")
+	}
+
+	i, err := g.intn(len(allowed))
+	if err != nil {
+		return "", err
+	}
+	return g.GenerateForRegion(allowed[i], formatted)
+}
+
+// GenerateWithPrefix creates a random valid CPF whose first digits are
+// prefix. The prefix holds up to 9 digits and may contain the dots and dashes
+// of the formatted CPF.
+func (g *Generator) GenerateWithPrefix(prefix string, formatted bool) (string, error) {
+	fixed, err := parseDigits(prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(fixed) > 9 {
+		return "", fmt.Errorf("invalid CPF prefix (must have at most 9 digits, got %d)", len(fixed))
+	}
+
+	digits9 := make([]int, 9)
+	copy(digits9, fixed)
+	if err := rollBase(digits9, func() error { return g.randomDigits(digits9[len(fixed):]) }); err != nil {
+		return "", err
+	}
+
+	dv, err := getCD(digits9)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate check digits: %w", err)
+	}
+	return buildCPF(digits9, dv, formatted)
+}
+
+// GenerateWithPattern creates a random valid CPF whose first 9 digits follow
+// pattern. The pattern has exactly 9 characters: digits are kept as they are,
+// while '#' and '?' are replaced by random digits.
+func (g *Generator) GenerateWithPattern(pattern string, formatted bool) (string, error) {
+	if len(pattern) != 9 {
+		return "", fmt.Errorf("invalid CPF pattern (must have 9 characters, got %d)", len(pattern))
+	}
+
+	for _, c := range pattern {
+		if (c < '0' || c > '9') && c != '#' && c != '?' {
+			return "", fmt.Errorf("invalid CPF pattern character %q (must be a digit, '#' or '?')", c)
+		}
+	}
+
+	digits9 := make([]int, 9)
+	err := rollBase(digits9, func() error {
+		if err := g.randomDigits(digits9); err != nil {
+			return err
+		}
+		for i, c := range pattern {
+			if c >= '0' && c <= '9' {
+				digits9[i] = int(c - '0')
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	dv, err := getCD(digits9)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate check digits: %w", err)
+	}
+	return buildCPF(digits9, dv, formatted)
+}
+
+// GenerateMixed generates count distinct CPFs, each of which is invalid with
+// probability invalidRate. Each result's Valid field records whether the CPF
+// is valid.
+func GenerateMixed(count int, invalidRate float64, formatted bool) ([]CPFResult, error) {
+	return defaultGenerator.GenerateMixed(count, invalidRate, formatted)
+}
+
+// GenerateMixed generates count distinct CPFs with g, each of which is
+// invalid with probability invalidRate.
+func (g *Generator) GenerateMixed(count int, invalidRate float64, formatted bool) ([]CPFResult, error) {
+	if !(invalidRate >= 0 && invalidRate <= 1) {
+		return nil, fmt.Errorf("invalid rate %v (must be between 0 and 1)", invalidRate)
+	}
+
+	cpfs, err := GenerateUnique(count, func() (string, error) {
+		roll, err := g.float64()
+		if err != nil {
+			return "", err
+		}
+		return g.Generate(formatted, roll < invalidRate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CPFResult, 0, len(cpfs))
+	for _, cpf := range cpfs {
+		results = append(results, GeneratedResult(cpf))
+	}
+	return results, nil
+}
+
+// float64 returns a random number in [0, 1).
+func (g *Generator) float64() (float64, error) {
+	if g.rng != nil {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.rng.Float64(), nil
+	}
+	n, err := cryptoRandInt(1 << 53)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return float64(n) / (1 << 53), nil
+}
@@ -0,0 +1,291 @@
+package cpf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSeededGeneratorDeterministic(t *testing.T) {
+	g1 := NewSeededGenerator(42)
+	g2 := NewSeededGenerator(42)
+
+	for i := 0; i < 100; i++ {
+		a, err := g1.Generate(true, i%2 == 0)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		b, err := g2.Generate(true, i%2 == 0)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if a != b {
+			t.Fatalf("generators with the same seed diverged at %d: %v != %v", i, a, b)
+		}
+	}
+}
+
+func TestSeededGeneratorDifferentSeeds(t *testing.T) {
+	a, _ := NewSeededGenerator(1).Generate(false, false)
+	b, _ := NewSeededGenerator(2).Generate(false, false)
+	if a == b {
+		t.Errorf("generators with different seeds produced the same CPF %v", a)
+	}
+}
+
+func TestGeneratorZeroValue(t *testing.T) {
+	var g Generator
+	got, err := g.Generate(false, false)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !ValidateCPF(got, false) {
+		t.Errorf("Generate() = %v, not a valid CPF", got)
+	}
+}
+
+func TestGeneratorGenerateN(t *testing.T) {
+	got, err := NewSeededGenerator(3).GenerateN(200)
+	if err != nil {
+		t.Fatalf("GenerateN() error = %v", err)
+	}
+	if len(got) != 200 {
+		t.Fatalf("GenerateN() returned %d CPFs, want 200", len(got))
+	}
+	seen := make(map[string]bool)
+	for _, c := range got {
+		if !ValidateCPF(c, true) {
+			t.Errorf("GenerateN() returned %q, which is not a valid formatted CPF", c)
+		}
+		if seen[c] {
+			t.Errorf("GenerateN() returned %q twice", c)
+		}
+		seen[c] = true
+	}
+}
+
+// TestGeneratorConcurrent shares generators between goroutines; run it with
+// -race to catch unsynchronized access to the random source.
+func TestGeneratorCountBetween(t *testing.T) {
+	g := NewGenerator(nil)
+	seen := make(map[int]bool)
+	for i := 0; i < 500; i++ {
+		n, err := g.CountBetween(5, 8)
+		if err != nil {
+			t.Fatalf("CountBetween() error = %v", err)
+		}
+		if n < 5 || n > 8 {
+			t.Fatalf("CountBetween(5, 8) = %d, out of range", n)
+		}
+		seen[n] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("CountBetween(5, 8) only returned %v", seen)
+	}
+
+	if n, err := g.CountBetween(3, 3); err != nil || n != 3 {
+		t.Errorf("CountBetween(3, 3) = %d, %v, want 3", n, err)
+	}
+	for _, r := range [][2]int{{0, 5}, {6, 5}, {-1, 2}} {
+		if _, err := g.CountBetween(r[0], r[1]); err == nil {
+			t.Errorf("CountBetween(%d, %d) error = nil, want error", r[0], r[1])
+		}
+	}
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	for name, g := range map[string]*Generator{
+		"crypto": NewGenerator(nil),
+		"seeded": NewSeededGenerator(11),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			errs := make(chan error, 32)
+			for i := 0; i < 32; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					cpfs, err := g.GenerateN(50)
+					if err != nil {
+						errs <- err
+						return
+					}
+					for _, c := range cpfs {
+						if !ValidateCPF(c, true) {
+							errs <- fmt.Errorf("generated invalid CPF %q", c)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGeneratorRerollsRepeatedDigits(t *testing.T) {
+	g := NewSeededGenerator(5)
+	generators := map[string]func() (string, error){
+		"random":  func() (string, error) { return g.Generate(false, false) },
+		"prefix":  func() (string, error) { return g.GenerateWithPrefix("11111111", false) },
+		"pattern": func() (string, error) { return g.GenerateWithPattern("2222#2222", false) },
+	}
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 2000; i++ {
+				got, err := generate()
+				if err != nil {
+					t.Fatalf("generate() error = %v", err)
+				}
+				if IsRepeated(got) || !ValidateCPF(got, false) {
+					t.Fatalf("generate() = %q, which is not a valid CPF", got)
+				}
+			}
+		})
+	}
+
+	for _, fixed := range []string{"111111111", "000.000.000"} {
+		if got, err := GenerateCPFWithPrefix(fixed, false); err == nil {
+			t.Errorf("GenerateCPFWithPrefix(%q) = %q, expected error", fixed, got)
+		}
+	}
+	if got, err := GenerateCPFWithPattern("333333333", false); err == nil {
+		t.Errorf("GenerateCPFWithPattern(333333333) = %q, expected error", got)
+	}
+}
+
+func TestGenerateMixed(t *testing.T) {
+	const count = 5000
+	for _, rate := range []float64{0, 0.2, 0.5, 1} {
+		results, err := NewSeededGenerator(7).GenerateMixed(count, rate, true)
+		if err != nil {
+			t.Fatalf("GenerateMixed(rate=%v) error = %v", rate, err)
+		}
+		if len(results) != count {
+			t.Fatalf("GenerateMixed(rate=%v) returned %d results, want %d", rate, len(results), count)
+		}
+
+		invalid := 0
+		for _, r := range results {
+			if r.Valid != ValidateCPF(r.CPF, false) {
+				t.Fatalf("result %+v has the wrong validity", r)
+			}
+			if !r.Valid {
+				invalid++
+			}
+		}
+		if got := float64(invalid) / count; got < rate-0.03 || got > rate+0.03 {
+			t.Errorf("GenerateMixed(rate=%v) invalid fraction = %v", rate, got)
+		}
+	}
+
+	for _, rate := range []float64{-0.1, 1.5, math.NaN()} {
+		if _, err := GenerateMixed(1, rate, true); err == nil {
+			t.Errorf("GenerateMixed(rate=%v) expected error", rate)
+		}
+	}
+}
+
+func TestGenerateExcludingRegions(t *testing.T) {
+	g := NewSeededGenerator(7)
+	exclude := []int{3, 7}
+	seen := make(map[int]bool)
+	for i := 0; i < 500; i++ {
+		generated, err := g.GenerateExcludingRegions(exclude, true)
+		if err != nil {
+			t.Fatalf("GenerateExcludingRegions() error = %v", err)
+		}
+		if !ValidateCPF(generated, false) {
+			t.Fatalf("GenerateExcludingRegions() = %q, not a valid CPF", generated)
+		}
+		code, _, _ := Region(generated)
+		if code == 3 || code == 7 {
+			t.Fatalf("GenerateExcludingRegions(%v) = %q, from region %d", exclude, generated, code)
+		}
+		seen[code] = true
+	}
+	if len(seen) != 8 {
+		t.Errorf("GenerateExcludingRegions(%v) only used regions %v", exclude, seen)
+	}
+
+	if got, err := g.GenerateExcludingRegions([]int{0, 1, 2, 3, 4, 5, 6, 7, 9}, false); err != nil || got[8] != '8' {
+		t.Errorf("GenerateExcludingRegions(all but 8) = %q, %v", got, err)
+	}
+	for _, exclude := range [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, {10}, {-1}} {
+		if _, err := g.GenerateExcludingRegions(exclude, false); err == nil {
+			t.Errorf("GenerateExcludingRegions(%v) error = nil, want error", exclude)
+		}
+	}
+}
+
+func TestGenerateCPFWithPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"", ""},
+		{"5", "5"},
+		{"529982", "529982"},
+		{"529.982.2", "5299822"},
+		{"529982247", "529982247"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				got, err := GenerateCPFWithPrefix(tt.prefix, i%2 == 0)
+				if err != nil {
+					t.Fatalf("GenerateCPFWithPrefix(%q) error = %v", tt.prefix, err)
+				}
+				if !strings.HasPrefix(UnformatCPF(got), tt.want) {
+					t.Fatalf("GenerateCPFWithPrefix(%q) = %q, want prefix %q", tt.prefix, got, tt.want)
+				}
+				if !ValidateCPF(got, false) {
+					t.Fatalf("GenerateCPFWithPrefix(%q) = %q, which is not valid", tt.prefix, got)
+				}
+			}
+		})
+	}
+
+	for _, prefix := range []string{"52998224725", "52a", "1234567890"} {
+		if _, err := GenerateCPFWithPrefix(prefix, true); err == nil {
+			t.Errorf("GenerateCPFWithPrefix(%q) expected error", prefix)
+		}
+	}
+}
+
+func TestGenerateCPFWithPattern(t *testing.T) {
+	patterns := []string{"0000#####", "12?45?78?", "#########", "987654321"}
+
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				got, err := GenerateCPFWithPattern(pattern, i%2 == 0)
+				if err != nil {
+					t.Fatalf("GenerateCPFWithPattern(%q) error = %v", pattern, err)
+				}
+				digits := UnformatCPF(got)
+				for j, c := range pattern {
+					if c != '#' && c != '?' && digits[j] != byte(c) {
+						t.Fatalf("GenerateCPFWithPattern(%q) = %q, digit %d = %c, want %c", pattern, got, j+1, digits[j], c)
+					}
+				}
+				if !ValidateCPF(got, false) {
+					t.Fatalf("GenerateCPFWithPattern(%q) = %q, which is not valid", pattern, got)
+				}
+			}
+		})
+	}
+
+	for _, pattern := range []string{"", "0000####", "0000######", "0000#x###", "000.###.###"} {
+		if _, err := GenerateCPFWithPattern(pattern, true); err == nil {
+			t.Errorf("GenerateCPFWithPattern(%q) expected error", pattern)
+		}
+	}
+}
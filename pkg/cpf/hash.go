@@ -0,0 +1,22 @@
+package cpf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashCPF returns the hex SHA-256 digest of salt followed by the digits of a
+// valid CPF, a stable pseudonym for joining datasets without storing the CPF
+// itself. Formatting does not affect the hash, so "529.982.247-25" and
+// "52998224725" hash alike. Invalid CPFs are rejected.
+func HashCPF(cpfStr, salt string) (string, error) {
+	digits, err := ToDigits(cpfStr)
+	if err != nil {
+		return "", err
+	}
+	if valid, reason := ValidateCPFDetailed(digits); !valid {
+		return "", reasonErrorf(reason, "invalid CPF number")
+	}
+	sum := sha256.Sum256([]byte(salt + digits))
+	return hex.EncodeToString(sum[:]), nil
+}
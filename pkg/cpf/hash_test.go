@@ -0,0 +1,35 @@
+package cpf
+
+import "testing"
+
+func TestHashCPF(t *testing.T) {
+	h1, err := HashCPF("529.982.247-25", "pepper")
+	if err != nil {
+		t.Fatalf("HashCPF() error = %v", err)
+	}
+	if len(h1) != 64 {
+		t.Errorf("HashCPF() = %q, want 64 hex digits", h1)
+	}
+
+	h2, err := HashCPF("52998224725", "pepper")
+	if err != nil {
+		t.Fatalf("HashCPF() error = %v", err)
+	}
+	if h1 != h2 {
+		t.Errorf("same CPF and salt hashed to %q and %q", h1, h2)
+	}
+
+	h3, err := HashCPF("52998224725", "salt")
+	if err != nil {
+		t.Fatalf("HashCPF() error = %v", err)
+	}
+	if h1 == h3 {
+		t.Errorf("different salts both hashed to %q", h1)
+	}
+
+	for _, input := range []string{"52998224724", "123", "111.111.111-11"} {
+		if _, err := HashCPF(input, "pepper"); err == nil {
+			t.Errorf("HashCPF(%q) error = nil, want error", input)
+		}
+	}
+}
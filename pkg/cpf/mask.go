@@ -0,0 +1,63 @@
+package cpf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaskOptions configures how MaskCPF redacts a CPF.
+type MaskOptions struct {
+	// VisibleStart is the number of leading digits kept visible.
+	VisibleStart int
+	// VisibleEnd is the number of trailing digits kept visible.
+	VisibleEnd int
+	// MaskChar replaces hidden digits. Defaults to '*'.
+	MaskChar rune
+}
+
+// DefaultMaskOptions keeps the first three and last three digits visible,
+// e.g. 529.***.**7-25.
+var DefaultMaskOptions = MaskOptions{VisibleStart: 3, VisibleEnd: 3, MaskChar: '*'}
+
+// MaskCPF hides the middle digits of a CPF. An 11-digit CPF is returned in
+// the ###.###.###-## format; any other input is masked digits-only. When the
+// input has no more digits than the visible window, every digit is masked so
+// that short inputs are never revealed in full.
+func MaskCPF(cpfStr string, opts MaskOptions) (string, error) {
+	if opts.VisibleStart < 0 || opts.VisibleEnd < 0 {
+		return "", fmt.Errorf("visible digit counts must not be negative")
+	}
+	if opts.MaskChar == 0 {
+		opts.MaskChar = '*'
+	}
+
+	digits := UnformatCPF(cpfStr)
+	if len(digits) == 0 {
+		return "", reasonErrorf(ErrNonNumeric, "invalid CPF number (no digits found)")
+	}
+
+	start, end := opts.VisibleStart, opts.VisibleEnd
+	if start+end >= len(digits) {
+		start, end = 0, 0
+	}
+
+	masked := strings.Builder{}
+	for i, ch := range digits {
+		if i < start || i >= len(digits)-end {
+			masked.WriteRune(ch)
+		} else {
+			masked.WriteRune(opts.MaskChar)
+		}
+	}
+
+	if len(digits) != 11 {
+		return masked.String(), nil
+	}
+	m := []rune(masked.String())
+	return fmt.Sprintf("%s.%s.%s-%s",
+		string(m[0:3]),
+		string(m[3:6]),
+		string(m[6:9]),
+		string(m[9:11]),
+	), nil
+}
@@ -0,0 +1,39 @@
+package cpf
+
+import (
+	"testing"
+)
+
+func TestMaskCPF(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		opts        MaskOptions
+		expected    string
+		expectError bool
+	}{
+		{"default options", "529.982.247-25", DefaultMaskOptions, "529.***.**7-25", false},
+		{"unformatted input", "52998224725", DefaultMaskOptions, "529.***.**7-25", false},
+		{"only last two", "52998224725", MaskOptions{VisibleEnd: 2}, "***.***.***-25", false},
+		{"custom mask char", "52998224725", MaskOptions{VisibleStart: 3, VisibleEnd: 2, MaskChar: '#'}, "529.###.###-25", false},
+		{"nothing visible", "52998224725", MaskOptions{}, "***.***.***-**", false},
+		{"short input", "52998", DefaultMaskOptions, "*****", false},
+		{"window equals length", "529982", DefaultMaskOptions, "******", false},
+		{"partial input", "5299822", DefaultMaskOptions, "529*822", false},
+		{"no digits", "abc", DefaultMaskOptions, "", true},
+		{"negative visible", "52998224725", MaskOptions{VisibleStart: -1}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MaskCPF(tt.input, tt.opts)
+			if (err != nil) != tt.expectError {
+				t.Errorf("MaskCPF() error = %v, expectError %v", err, tt.expectError)
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("MaskCPF() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
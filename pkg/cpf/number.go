@@ -0,0 +1,91 @@
+package cpf
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// CPFNumber is a validated CPF, stored as digits only. It marshals to JSON in
+// the ###.###.###-## format and is stored in SQL databases as digits only.
+// Unmarshaling or scanning an invalid CPF returns an error.
+type CPFNumber string
+
+// NewCPFNumber validates s and returns it as a CPFNumber.
+func NewCPFNumber(s string) (CPFNumber, error) {
+	valid, reason := ValidateCPFDetailed(s)
+	if !valid {
+		return "", fmt.Errorf("invalid CPF number %q (%w)", s, reason)
+	}
+	return CPFNumber(UnformatCPF(s)), nil
+}
+
+// String returns the CPF in the ###.###.###-## format.
+func (n CPFNumber) String() string {
+	formatted, err := FormatCPF(string(n))
+	if err != nil {
+		return string(n)
+	}
+	return formatted
+}
+
+// Digits returns the CPF as digits only.
+func (n CPFNumber) Digits() string {
+	return UnformatCPF(string(n))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n CPFNumber) MarshalJSON() ([]byte, error) {
+	if n == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *CPFNumber) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("CPF must be a JSON string: %w", err)
+	}
+	parsed, err := NewCPFNumber(s)
+	if err != nil {
+		return err
+	}
+	*n = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner.
+func (n *CPFNumber) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*n = ""
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into CPFNumber", src)
+	}
+
+	parsed, err := NewCPFNumber(s)
+	if err != nil {
+		return err
+	}
+	*n = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, storing the CPF as digits only.
+func (n CPFNumber) Value() (driver.Value, error) {
+	if n == "" {
+		return nil, nil
+	}
+	return n.Digits(), nil
+}
@@ -0,0 +1,103 @@
+package cpf
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+)
+
+var (
+	_ json.Marshaler   = CPFNumber("")
+	_ json.Unmarshaler = (*CPFNumber)(nil)
+	_ sql.Scanner      = (*CPFNumber)(nil)
+	_ driver.Valuer    = CPFNumber("")
+)
+
+func TestNewCPFNumber(t *testing.T) {
+	n, err := NewCPFNumber("529.982.247-25")
+	if err != nil {
+		t.Fatalf("NewCPFNumber() error = %v", err)
+	}
+	if n.Digits() != "52998224725" || n.String() != "529.982.247-25" {
+		t.Errorf("NewCPFNumber() = %q (%s), want digits 52998224725", string(n), n)
+	}
+
+	for _, input := range []string{"52998224726", "11111111111", "123", ""} {
+		if _, err := NewCPFNumber(input); err == nil {
+			t.Errorf("NewCPFNumber(%q) expected error", input)
+		}
+	}
+}
+
+func TestCPFNumberJSONRoundTrip(t *testing.T) {
+	type person struct {
+		Name string    `json:"name"`
+		CPF  CPFNumber `json:"cpf"`
+	}
+
+	var p person
+	if err := json.Unmarshal([]byte(`{"name":"Ana","cpf":"52998224725"}`), &p); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if p.CPF != "52998224725" {
+		t.Errorf("unmarshaled CPF = %q, want digits only", string(p.CPF))
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if want := `{"name":"Ana","cpf":"529.982.247-25"}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var again person
+	if err := json.Unmarshal(data, &again); err != nil || again != p {
+		t.Errorf("round trip = %+v (error %v), want %+v", again, err, p)
+	}
+
+	for _, body := range []string{`{"cpf":"52998224726"}`, `{"cpf":123}`, `{"cpf":""}`} {
+		var invalid person
+		if err := json.Unmarshal([]byte(body), &invalid); err == nil {
+			t.Errorf("json.Unmarshal(%s) expected error", body)
+		}
+	}
+}
+
+func TestCPFNumberSQL(t *testing.T) {
+	n, err := NewCPFNumber("111.444.777-35")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := n.Value()
+	if err != nil || value != "11144477735" {
+		t.Errorf("Value() = %v (error %v), want digits only", value, err)
+	}
+
+	// Scan as a database driver would, with both string and []byte values
+	for _, src := range []any{value, []byte("11144477735")} {
+		var scanned CPFNumber
+		if err := scanned.Scan(src); err != nil {
+			t.Fatalf("Scan(%v) error = %v", src, err)
+		}
+		if scanned != n {
+			t.Errorf("Scan(%v) = %q, want %q", src, string(scanned), string(n))
+		}
+	}
+
+	var empty CPFNumber
+	if err := empty.Scan(nil); err != nil || empty != "" {
+		t.Errorf("Scan(nil) = %q (error %v), want empty", string(empty), err)
+	}
+	if value, err := empty.Value(); err != nil || value != nil {
+		t.Errorf("Value() of empty = %v (error %v), want nil", value, err)
+	}
+
+	for _, src := range []any{"11144477734", 11144477735} {
+		var invalid CPFNumber
+		if err := invalid.Scan(src); err == nil {
+			t.Errorf("Scan(%v) expected error", src)
+		}
+	}
+}
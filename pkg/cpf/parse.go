@@ -0,0 +1,36 @@
+package cpf
+
+// ParsedCPF holds the components of an 11-digit CPF.
+type ParsedCPF struct {
+	// Base is the first nine digits.
+	Base string `json:"base"`
+	// CheckDigits is the two check digits (DV) the CPF carries, which are
+	// only correct when Valid is set.
+	CheckDigits string `json:"check_digits"`
+	// Region is the fiscal region code, the 9th digit.
+	Region int `json:"region"`
+	// Valid reports whether the CPF passes validation.
+	Valid bool `json:"valid"`
+	// Formatted is the CPF in the ###.###.###-## format.
+	Formatted string `json:"formatted"`
+}
+
+// ParseCPF splits a CPF into its components. Formatting is ignored, and the
+// CPF need not be valid, but it must have exactly 11 digits.
+func ParseCPF(cpfStr string) (ParsedCPF, error) {
+	digits, err := ToDigits(cpfStr)
+	if err != nil {
+		return ParsedCPF{}, err
+	}
+	formatted, err := FormatCPF(digits)
+	if err != nil {
+		return ParsedCPF{}, err
+	}
+	return ParsedCPF{
+		Base:        digits[:9],
+		CheckDigits: digits[9:],
+		Region:      int(digits[8] - '0'),
+		Valid:       ValidateCPF(digits, false),
+		Formatted:   formatted,
+	}, nil
+}
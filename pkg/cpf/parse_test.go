@@ -0,0 +1,33 @@
+package cpf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCPF(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ParsedCPF
+	}{
+		{"529.982.247-25", ParsedCPF{Base: "529982247", CheckDigits: "25", Region: 7, Valid: true, Formatted: "529.982.247-25"}},
+		{"11144477735", ParsedCPF{Base: "111444777", CheckDigits: "35", Region: 7, Valid: true, Formatted: "111.444.777-35"}},
+		{"123.456.788-00", ParsedCPF{Base: "123456788", CheckDigits: "00", Region: 8, Valid: false, Formatted: "123.456.788-00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseCPF(tt.input)
+			if err != nil {
+				t.Fatalf("ParseCPF() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCPF() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseCPF("529.982.247-2"); !errors.Is(err, ErrLength) {
+		t.Errorf("ParseCPF() of 10 digits error = %v, want ErrLength", err)
+	}
+}
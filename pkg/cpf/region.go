@@ -0,0 +1,104 @@
+package cpf
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// regions maps the 9th digit of a CPF to the fiscal region that issued it.
+var regions = [10]string{
+	0: "Rio Grande do Sul",
+	1: "Distrito Federal, Goiás, Mato Grosso, Mato Grosso do Sul, Tocantins",
+	2: "Acre, Amapá, Amazonas, Pará, Rondônia, Roraima",
+	3: "Ceará, Maranhão, Piauí",
+	4: "Alagoas, Paraíba, Pernambuco, Rio Grande do Norte",
+	5: "Bahia, Sergipe",
+	6: "Minas Gerais",
+	7: "Espírito Santo, Rio de Janeiro",
+	8: "São Paulo",
+	9: "Paraná, Santa Catarina",
+}
+
+// Region returns the fiscal region code (the 9th digit) of a CPF along with
+// a description of the states it covers.
+func Region(cpfStr string) (int, string, error) {
+	digits := UnformatCPF(cpfStr)
+	if len(digits) != 11 {
+		return 0, "", lengthError(len(digits))
+	}
+	code := int(digits[8] - '0')
+	return code, regions[code], nil
+}
+
+// RegionName returns the description of a fiscal region code.
+func RegionName(code int) (string, error) {
+	if code < 0 || code > 9 {
+		return "", fmt.Errorf("invalid region %d (must be between 0 and 9)", code)
+	}
+	return regions[code], nil
+}
+
+// RegionHistogram counts the CPFs issued by each fiscal region code. Entries
+// that don't have 11 digits are skipped.
+func RegionHistogram(cpfs []string) map[int]int {
+	histogram := make(map[int]int)
+	for _, c := range cpfs {
+		code, _, err := Region(c)
+		if err != nil {
+			continue
+		}
+		histogram[code]++
+	}
+	return histogram
+}
+
+// ValidateRegion reports whether the CPF was issued by the fiscal region
+// expectedRegion, such as the one matching a declared state. It returns an
+// error if the CPF itself is invalid.
+func ValidateRegion(cpfStr string, expectedRegion int) (bool, error) {
+	if expectedRegion < 0 || expectedRegion > 9 {
+		return false, fmt.Errorf("invalid region %d (must be between 0 and 9)", expectedRegion)
+	}
+	if valid, reason := ValidateCPFDetailed(cpfStr); !valid {
+		return false, fmt.Errorf("invalid CPF %q (%w)", cpfStr, reason)
+	}
+	code, _, err := Region(cpfStr)
+	if err != nil {
+		return false, err
+	}
+	return code == expectedRegion, nil
+}
+
+// RegionProcessor returns a processor that validates CPFs like
+// ValidateProcessor, but also rejects valid CPFs issued by a fiscal region
+// other than region, with the reason "region".
+func RegionProcessor(region int) func(string) CPFResult {
+	return func(cpf string) CPFResult {
+		result := ValidateProcessor(cpf)
+		if !result.Valid {
+			return result
+		}
+		if match, err := ValidateRegion(cpf, region); err != nil || !match {
+			result.Valid = false
+			result.Reason = ErrRegion.String()
+		}
+		return result
+	}
+}
+
+// GroupByRegion buckets the CPFs of valid results by fiscal region code, from
+// "0" to "9", and puts the CPFs of invalid results under "invalid". Only
+// buckets holding at least one CPF are present, each in the input order.
+func GroupByRegion(results []CPFResult) map[string][]string {
+	groups := make(map[string][]string)
+	for _, r := range results {
+		key := "invalid"
+		if r.Valid {
+			if code, _, err := Region(r.CPF); err == nil {
+				key = strconv.Itoa(code)
+			}
+		}
+		groups[key] = append(groups[key], r.CPF)
+	}
+	return groups
+}
@@ -0,0 +1,154 @@
+package cpf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantCode int
+		wantDesc string
+	}{
+		{"region 0", "123.456.780-00", 0, "Rio Grande do Sul"},
+		{"region 1", "123.456.781-00", 1, "Distrito Federal, Goiás, Mato Grosso, Mato Grosso do Sul, Tocantins"},
+		{"region 2", "123.456.782-00", 2, "Acre, Amapá, Amazonas, Pará, Rondônia, Roraima"},
+		{"region 3", "123.456.783-00", 3, "Ceará, Maranhão, Piauí"},
+		{"region 4", "123.456.784-00", 4, "Alagoas, Paraíba, Pernambuco, Rio Grande do Norte"},
+		{"region 5", "123.456.785-00", 5, "Bahia, Sergipe"},
+		{"region 6", "123.456.786-00", 6, "Minas Gerais"},
+		{"region 7", "111.444.777-35", 7, "Espírito Santo, Rio de Janeiro"},
+		{"region 8", "12345678809", 8, "São Paulo"},
+		{"region 9", "123.456.789-09", 9, "Paraná, Santa Catarina"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, desc, err := Region(tt.input)
+			if err != nil {
+				t.Fatalf("Region() error = %v", err)
+			}
+			if code != tt.wantCode || desc != tt.wantDesc {
+				t.Errorf("Region() = (%d, %q), want (%d, %q)", code, desc, tt.wantCode, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestRegionInvalidInput(t *testing.T) {
+	for _, input := range []string{"", "1234567890", "123456789012", "abc"} {
+		if _, _, err := Region(input); err == nil {
+			t.Errorf("Region(%q) expected error", input)
+		}
+	}
+}
+
+func TestRegionHistogram(t *testing.T) {
+	cpfs := make([]string, 500)
+	for i := range cpfs {
+		generated, err := GenerateCPF(i%2 == 0, false)
+		if err != nil {
+			t.Fatalf("GenerateCPF() error = %v", err)
+		}
+		cpfs[i] = generated
+	}
+
+	histogram := RegionHistogram(append(cpfs, "123"))
+	total := 0
+	for code, n := range histogram {
+		if code < 0 || code > 9 {
+			t.Errorf("histogram has region %d", code)
+		}
+		total += n
+	}
+	if total != len(cpfs) {
+		t.Errorf("histogram counts sum to %d, want %d", total, len(cpfs))
+	}
+
+	if got := RegionHistogram([]string{"111.444.777-35", "12345678809", "11144477735"}); got[7] != 2 || got[8] != 1 {
+		t.Errorf("RegionHistogram() = %v, want map[7:2 8:1]", got)
+	}
+}
+
+func TestValidateRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		cpf     string
+		region  int
+		want    bool
+		wantErr bool
+	}{
+		{"match", "529.982.247-25", 7, true, false},
+		{"match unformatted", "12345678810", 8, true, false},
+		{"mismatch", "529.982.247-25", 8, false, false},
+		{"invalid CPF", "529.982.247-24", 7, false, true},
+		{"short CPF", "123", 1, false, true},
+		{"region out of range", "529.982.247-25", 10, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateRegion(tt.cpf, tt.region)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateRegion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateRegion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if r := RegionProcessor(8)("529.982.247-25"); r.Valid || r.Reason != "region" {
+		t.Errorf("RegionProcessor(8) = %+v, want invalid for region", r)
+	}
+	if r := RegionProcessor(7)("529.982.247-25"); !r.Valid {
+		t.Errorf("RegionProcessor(7) = %+v, want valid", r)
+	}
+}
+
+func TestGroupByRegion(t *testing.T) {
+	sp, err := CompleteCPF("123456788", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := ValidateAll([]string{"529.982.247-25", sp, "123", "111.444.777-35", "52998224724"}, 1)
+
+	got := GroupByRegion(results)
+	want := map[string][]string{
+		"7":       {"529.982.247-25", "111.444.777-35"},
+		"8":       {sp},
+		"invalid": {"123", "52998224724"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupByRegion() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateCPFForRegion(t *testing.T) {
+	for region := 0; region <= 9; region++ {
+		got, err := GenerateCPFForRegion(region, region%2 == 0)
+		if err != nil {
+			t.Fatalf("GenerateCPFForRegion(%d) error = %v", region, err)
+		}
+		if !ValidateCPF(got, false) {
+			t.Errorf("GenerateCPFForRegion(%d) = %v, not a valid CPF", region, got)
+		}
+		code, _, err := Region(got)
+		if err != nil {
+			t.Fatalf("Region(%v) error = %v", got, err)
+		}
+		if code != region {
+			t.Errorf("Region(%v) = %d, want %d", got, code, region)
+		}
+	}
+}
+
+func TestGenerateCPFForRegionOutOfRange(t *testing.T) {
+	for _, region := range []int{-1, 10} {
+		if _, err := GenerateCPFForRegion(region, false); err == nil {
+			t.Errorf("GenerateCPFForRegion(%d) expected error", region)
+		}
+	}
+}
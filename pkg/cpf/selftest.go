@@ -0,0 +1,74 @@
+package cpf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// selfTestValid and selfTestInvalid are known vectors for SelfTest.
+var (
+	selfTestValid = []string{
+		"529.982.247-25",
+		"52998224725",
+		"111.444.777-35",
+		"123.456.789-09",
+		"987.654.321-00",
+	}
+	selfTestInvalid = []string{
+		"529.982.247-24",
+		"529.982.247-15",
+		"111.444.777-53",
+		"111.111.111-11",
+		"000.000.000-00",
+		"5299822472",
+		"529982247250",
+		"",
+	}
+)
+
+// selfTestRounds is how many CPFs SelfTest generates of each kind.
+const selfTestRounds = 100
+
+// SelfTest checks the CPF algorithm against known valid and invalid CPFs and
+// round-trips generated CPFs through validation and formatting. It returns
+// an error describing every failed expectation, or nil when all pass.
+func SelfTest() error {
+	var errs []error
+	for _, cpf := range selfTestValid {
+		if !ValidateCPF(cpf, false) {
+			errs = append(errs, fmt.Errorf("known valid CPF %q failed validation", cpf))
+		}
+	}
+	for _, cpf := range selfTestInvalid {
+		if ValidateCPF(cpf, false) {
+			errs = append(errs, fmt.Errorf("known invalid CPF %q passed validation", cpf))
+		}
+	}
+	if dv, err := CalculateCheckDigits("529982247"); err != nil || dv != "25" {
+		errs = append(errs, fmt.Errorf("check digits of 529982247 = %q (error %v), want 25", dv, err))
+	}
+
+	g := NewSeededGenerator(1)
+	for i := 0; i < selfTestRounds; i++ {
+		valid, err := g.Generate(false, false)
+		if err != nil {
+			return fmt.Errorf("generating a CPF: %w", err)
+		}
+		if !ValidateCPF(valid, false) {
+			errs = append(errs, fmt.Errorf("generated CPF %q failed validation", valid))
+		}
+		formatted, err := FormatCPF(valid)
+		if err != nil || UnformatCPF(formatted) != valid || !ValidateCPF(formatted, false) {
+			errs = append(errs, fmt.Errorf("generated CPF %q didn't survive formatting as %q (error %v)", valid, formatted, err))
+		}
+
+		invalid, err := g.Generate(true, true)
+		if err != nil {
+			return fmt.Errorf("generating a CPF: %w", err)
+		}
+		if ValidateCPF(invalid, false) {
+			errs = append(errs, fmt.Errorf("generated invalid CPF %q passed validation", invalid))
+		}
+	}
+	return errors.Join(errs...)
+}
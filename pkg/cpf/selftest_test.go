@@ -0,0 +1,9 @@
+package cpf
+
+import "testing"
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Errorf("SelfTest() error = %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package cpf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// sqlIdentifier matches the table and column names accepted by RenderSQL: a
+// plain identifier, optionally qualified by a schema, e.g. public.users.
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// RenderSQL writes one INSERT statement per result to w, such as
+// "INSERT INTO users (cpf) VALUES ('529.982.247-25');", for seeding a
+// database. Single quotes in the CPF are doubled; table and column must be
+// plain identifiers.
+func RenderSQL(results []CPFResult, table, column string, w io.Writer) error {
+	return RenderSQLStreamTo(w, sliceChan(results), table, column)
+}
+
+// RenderSQLStream writes results as INSERT statements to a file or stdout as
+// they arrive on the channel
+func RenderSQLStream(results <-chan CPFResult, table, column, outputFile string) error {
+	return writeStream(results, outputFile, func(w io.Writer, results <-chan CPFResult) error {
+		return RenderSQLStreamTo(w, results, table, column)
+	})
+}
+
+// RenderSQLStreamTo writes results as INSERT statements to w as they arrive
+// on the channel, one line per result
+func RenderSQLStreamTo(w io.Writer, results <-chan CPFResult, table, column string) error {
+	if !sqlIdentifier.MatchString(table) {
+		return fmt.Errorf("invalid SQL table name '%s'", table)
+	}
+	if !sqlIdentifier.MatchString(column) {
+		return fmt.Errorf("invalid SQL column name '%s'", column)
+	}
+
+	bw := bufio.NewWriter(w)
+	for result := range results {
+		value := strings.ReplaceAll(result.CPF, "'", "''")
+		fmt.Fprintf(bw, "INSERT INTO %s (%s) VALUES ('%s');\n", table, column, value)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return nil
+}
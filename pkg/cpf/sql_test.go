@@ -0,0 +1,35 @@
+package cpf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSQL(t *testing.T) {
+	results := []CPFResult{{CPF: "529.982.247-25"}, {CPF: "11144477735"}, {CPF: "x'); DROP TABLE users; --"}}
+
+	var buf strings.Builder
+	if err := RenderSQL(results, "users", "cpf", &buf); err != nil {
+		t.Fatalf("RenderSQL() error = %v", err)
+	}
+	want := "INSERT INTO users (cpf) VALUES ('529.982.247-25');\n" +
+		"INSERT INTO users (cpf) VALUES ('11144477735');\n" +
+		"INSERT INTO users (cpf) VALUES ('x''); DROP TABLE users; --');\n"
+	if buf.String() != want {
+		t.Errorf("RenderSQL() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := RenderSQL(results[:1], "public.people", "tax_id", &buf); err != nil {
+		t.Fatalf("RenderSQL() error = %v", err)
+	}
+	if want := "INSERT INTO public.people (tax_id) VALUES ('529.982.247-25');\n"; buf.String() != want {
+		t.Errorf("RenderSQL() = %q, want %q", buf.String(), want)
+	}
+
+	for _, names := range [][2]string{{"users; --", "cpf"}, {"users", "cpf)"}, {"", "cpf"}} {
+		if err := RenderSQL(results, names[0], names[1], &buf); err == nil {
+			t.Errorf("RenderSQL(table %q, column %q) error = nil, want error", names[0], names[1])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package cpf
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+)
+
+// GenerateCPFStream yields count random CPFs one at a time, so that large
+// batches can be consumed without holding them all in memory. Generation
+// stops at the first error, which is yielded with an empty CPF.
+func GenerateCPFStream(count int, formatted, invalid bool) iter.Seq2[string, error] {
+	return defaultGenerator.Stream(count, formatted, invalid)
+}
+
+// Stream yields count random CPFs generated by g one at a time.
+func (g *Generator) Stream(count int, formatted, invalid bool) iter.Seq2[string, error] {
+	return GenerateStream(count, func() (string, error) {
+		return g.Generate(formatted, invalid)
+	})
+}
+
+// GenerateStream yields count CPFs produced by next. Generation stops at the
+// first error, which is yielded with an empty CPF.
+func GenerateStream(count int, next func() (string, error)) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for i := 0; i < count; i++ {
+			cpf, err := next()
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if !yield(cpf, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GenerateUniqueStream is like GenerateStream but skips CPFs that were
+// already yielded, regenerating on collisions. It yields an error if next
+// keeps returning duplicates, which happens when the space of possible values
+// is smaller than count.
+func GenerateUniqueStream(count int, next func() (string, error)) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		seen := make(map[string]struct{})
+		retries := 0
+		for emitted := 0; emitted < count; {
+			cpf, err := next()
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			key := UnformatCPF(cpf)
+			if _, ok := seen[key]; ok {
+				retries++
+				if retries > maxUniqueRetries {
+					yield("", fmt.Errorf("could not generate %d unique CPFs (got %d)", count, emitted))
+					return
+				}
+				continue
+			}
+
+			retries = 0
+			seen[key] = struct{}{}
+			emitted++
+			if !yield(cpf, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GenerateRangeStream is like GenerateRange but yields the CPFs one at a
+// time, so that the whole range of nearly a billion bases can be written out
+// in constant memory. An invalid range is yielded as an error before any CPF.
+func GenerateRangeStream(from, to int, formatted bool) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if err := checkRange(from, to); err != nil {
+			yield("", err)
+			return
+		}
+		for n := from; n <= to; n++ {
+			base := fmt.Sprintf("%09d", n)
+			if IsRepeated(base) {
+				continue
+			}
+			cpf, err := CompleteCPF(base, formatted)
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if !yield(cpf, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GenerateUntil writes random CPFs to w, one per line, as fast as it can
+// until ctx is done, then flushes what it has buffered and returns ctx's
+// error. The CPFs are not checked for duplicates, so memory stays flat.
+func GenerateUntil(ctx context.Context, formatted, invalid bool, w io.Writer) error {
+	return defaultGenerator.GenerateUntil(ctx, formatted, invalid, w)
+}
+
+// GenerateUntil writes random CPFs generated by g to w until ctx is done.
+func (g *Generator) GenerateUntil(ctx context.Context, formatted, invalid bool, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for ctx.Err() == nil {
+		cpf, err := g.Generate(formatted, invalid)
+		if err != nil {
+			bw.Flush()
+			return err
+		}
+		bw.WriteString(cpf)
+		if err := bw.WriteByte('\n'); err != nil {
+			return fmt.Errorf("error writing output: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+	return ctx.Err()
+}
+
+// GenerateAtRate writes random CPFs to w, one per line, at rate CPFs per
+// second until ctx is done, which makes it a simple traffic generator. It
+// returns ctx's error once ctx is done.
+func GenerateAtRate(ctx context.Context, rate int, formatted, invalid bool, w io.Writer) error {
+	return defaultGenerator.GenerateAtRate(ctx, rate, formatted, invalid, w)
+}
+
+// GenerateAtRate writes random CPFs generated by g to w at rate CPFs per
+// second until ctx is done.
+func (g *Generator) GenerateAtRate(ctx context.Context, rate int, formatted, invalid bool, w io.Writer) error {
+	if rate <= 0 || rate > int(time.Second) {
+		return fmt.Errorf("invalid rate %d (must be between 1 and %d per second)", rate, int(time.Second))
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		cpf, err := g.Generate(formatted, invalid)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, cpf); err != nil {
+			return fmt.Errorf("error writing output: %w", err)
+		}
+	}
+}
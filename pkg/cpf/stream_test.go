@@ -0,0 +1,161 @@
+package cpf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateCPFStream(t *testing.T) {
+	count := 0
+	for cpf, err := range GenerateCPFStream(250, true, false) {
+		if err != nil {
+			t.Fatalf("GenerateCPFStream() error = %v", err)
+		}
+		if !ValidateCPF(cpf, false) {
+			t.Errorf("GenerateCPFStream() yielded invalid CPF %v", cpf)
+		}
+		count++
+	}
+	if count != 250 {
+		t.Errorf("GenerateCPFStream() yielded %d CPFs, want 250", count)
+	}
+}
+
+func TestGenerateStreamEarlyBreak(t *testing.T) {
+	calls := 0
+	next := func() (string, error) {
+		calls++
+		return GenerateCPF(false, false)
+	}
+
+	seen := 0
+	for range GenerateStream(1000, next) {
+		seen++
+		if seen == 5 {
+			break
+		}
+	}
+	if calls != 5 {
+		t.Errorf("GenerateStream() called next %d times after break, want 5", calls)
+	}
+}
+
+func TestGenerateStreamError(t *testing.T) {
+	wantErr := errors.New("boom")
+	next := func() (string, error) { return "", wantErr }
+
+	for cpf, err := range GenerateStream(3, next) {
+		if !errors.Is(err, wantErr) || cpf != "" {
+			t.Fatalf("GenerateStream() yielded (%q, %v), want (\"\", %v)", cpf, err, wantErr)
+		}
+	}
+}
+
+func TestGenerateUniqueStream(t *testing.T) {
+	seen := make(map[string]struct{})
+	for cpf, err := range GenerateUniqueStream(2000, func() (string, error) { return GenerateCPF(false, false) }) {
+		if err != nil {
+			t.Fatalf("GenerateUniqueStream() error = %v", err)
+		}
+		if _, ok := seen[cpf]; ok {
+			t.Fatalf("GenerateUniqueStream() yielded duplicate %v", cpf)
+		}
+		seen[cpf] = struct{}{}
+	}
+	if len(seen) != 2000 {
+		t.Errorf("GenerateUniqueStream() yielded %d CPFs, want 2000", len(seen))
+	}
+}
+
+func TestGenerateRangeStream(t *testing.T) {
+	// The full range of bases is consumed lazily: breaking early is cheap
+	var got []string
+	for cpf, err := range GenerateRangeStream(0, maxBase, false) {
+		if err != nil {
+			t.Fatalf("GenerateRangeStream() error = %v", err)
+		}
+		got = append(got, cpf)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if want := []string{"00000000191", "00000000272", "00000000353"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateRangeStream() = %v, want %v", got, want)
+	}
+
+	for cpf, err := range GenerateRangeStream(10, 9, false) {
+		if err == nil || cpf != "" {
+			t.Fatalf("GenerateRangeStream(10, 9) yielded (%q, %v), want an error", cpf, err)
+		}
+	}
+}
+
+func TestGenerateUntil(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := GenerateUntil(ctx, true, false, &buf); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateUntil() error = %v, want %v", err, context.Canceled)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("GenerateUntil() wrote %q with an already cancelled context", buf.String())
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	buf.Reset()
+	done := make(chan error)
+	go func() { done <- NewSeededGenerator(1).GenerateUntil(ctx, false, false, &buf) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("GenerateUntil() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GenerateUntil() kept running after its context was done")
+	}
+
+	// Everything buffered is flushed, so the output ends with a whole line
+	if buf.Len() == 0 || !strings.HasSuffix(buf.String(), "\n") {
+		t.Fatalf("GenerateUntil() output ends with %q", buf.String()[max(0, buf.Len()-20):])
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if !ValidateCPF(line, false) {
+			t.Fatalf("GenerateUntil() wrote %q, not a valid CPF", line)
+		}
+	}
+}
+
+func TestGenerateAtRate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	var buf bytes.Buffer
+	err := GenerateAtRate(ctx, 100, true, false, &buf)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GenerateAtRate() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	// 50 ticks fit in 500ms; leave room for a slow scheduler
+	if len(lines) < 25 || len(lines) > 51 {
+		t.Errorf("GenerateAtRate() wrote %d CPFs in 500ms at 100/s, want about 50", len(lines))
+	}
+	for _, line := range lines {
+		if !ValidateCPF(line, true) {
+			t.Fatalf("GenerateAtRate() wrote %q, not a valid formatted CPF", line)
+		}
+	}
+
+	for _, rate := range []int{0, -1} {
+		if err := GenerateAtRate(context.Background(), rate, true, false, io.Discard); err == nil {
+			t.Errorf("GenerateAtRate(rate=%d) expected error", rate)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cpf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Validator checks CPF numbers against a configurable set of rules.
+type Validator struct {
+	// LengthOnly skips the check digit verification, accepting any CPF with
+	// 11 digits.
+	LengthOnly bool
+	// RejectRepeated rejects CPFs made of a single repeated digit, such as
+	// 111.111.111-11, which pass the check digit algorithm.
+	RejectRepeated bool
+	// AllowAllZeros accepts 000.000.000-00 even when RejectRepeated is set,
+	// for systems that use it as a sentinel for a missing CPF.
+	AllowAllZeros bool
+	// RejectBlocklisted rejects CPFs in the blocklist, such as
+	// 123.456.789-09, which are valid but widely used as sample data.
+	RejectBlocklisted bool
+}
+
+// Option configures a Validator.
+type Option func(*Validator)
+
+// WithLengthOnly sets whether the Validator only checks the CPF length.
+func WithLengthOnly(lengthOnly bool) Option {
+	return func(v *Validator) {
+		v.LengthOnly = lengthOnly
+	}
+}
+
+// WithRejectRepeated sets whether the Validator rejects repeated-digit CPFs.
+func WithRejectRepeated(reject bool) Option {
+	return func(v *Validator) {
+		v.RejectRepeated = reject
+	}
+}
+
+// WithAllowAllZeros sets whether the Validator accepts the all-zeros CPF.
+func WithAllowAllZeros(allow bool) Option {
+	return func(v *Validator) {
+		v.AllowAllZeros = allow
+	}
+}
+
+// WithRejectBlocklisted sets whether the Validator rejects blocklisted CPFs.
+func WithRejectBlocklisted(reject bool) Option {
+	return func(v *Validator) {
+		v.RejectBlocklisted = reject
+	}
+}
+
+// NewValidator creates a Validator. Without options it performs the full
+// validation, rejecting repeated-digit CPFs.
+func NewValidator(opts ...Option) *Validator {
+	v := &Validator{RejectRepeated: true}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Validate checks if the provided CPF string is valid.
+func (v *Validator) Validate(cpfStr string) bool {
+	valid, _ := v.ValidateDetailed(cpfStr)
+	return valid
+}
+
+// ValidateDetailed checks if the provided CPF string is valid and reports the
+// reason when it is not.
+func (v *Validator) ValidateDetailed(cpfStr string) (bool, ValidationError) {
+	unformatted := UnformatCPF(cpfStr)
+	if len(unformatted) == 0 && strings.TrimSpace(cpfStr) != "" {
+		return false, ErrNonNumeric
+	}
+	if len(unformatted) != 11 {
+		return false, ErrLength
+	}
+	if v.RejectRepeated && IsRepeated(unformatted) && !(v.AllowAllZeros && unformatted == "00000000000") {
+		return false, ErrRepeated
+	}
+	if v.RejectBlocklisted && IsBlocklisted(unformatted) {
+		return false, ErrBlocklisted
+	}
+
+	if v.LengthOnly {
+		return true, ErrNone
+	}
+
+	number9 := unformatted[:9]
+	dv2 := unformatted[9:11]
+
+	var digits9 []int
+	for _, ch := range number9 {
+		d, err := strconv.Atoi(string(ch))
+		if err != nil {
+			return false, ErrNonNumeric
+		}
+		digits9 = append(digits9, d)
+	}
+
+	cd, err := getCD(digits9)
+	if err != nil {
+		return false, ErrLength
+	}
+	trueDV := fmt.Sprintf("%d%d", cd[0], cd[1])
+
+	if dv2 != trueDV {
+		return false, ErrCheckDigit
+	}
+	return true, ErrNone
+}
@@ -0,0 +1,49 @@
+package cpf
+
+import (
+	"testing"
+)
+
+func TestValidatorOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		input    string
+		expected bool
+	}{
+		{"default valid", nil, "111.444.777-35", true},
+		{"default bad DV", nil, "111.444.777-34", false},
+		{"default repeated", nil, "00000000000", false},
+		{"length only bad DV", []Option{WithLengthOnly(true)}, "111.444.777-34", true},
+		{"length only short", []Option{WithLengthOnly(true)}, "1114447773", false},
+		{"length only repeated", []Option{WithLengthOnly(true)}, "11111111111", false},
+		{"allow repeated", []Option{WithRejectRepeated(false)}, "00000000000", true},
+		{"allow repeated bad DV", []Option{WithRejectRepeated(false)}, "11111111112", false},
+		{"length only allow repeated", []Option{WithLengthOnly(true), WithRejectRepeated(false)}, "22222222222", true},
+		{"allow all zeros", []Option{WithAllowAllZeros(true)}, "000.000.000-00", true},
+		{"allow all zeros other repeated", []Option{WithAllowAllZeros(true)}, "11111111111", false},
+		{"forbid all zeros", []Option{WithAllowAllZeros(false)}, "000.000.000-00", false},
+		{"length only allow repeated short", []Option{WithLengthOnly(true), WithRejectRepeated(false)}, "2222222222", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(tt.opts...)
+			if got := v.Validate(tt.input); got != tt.expected {
+				t.Errorf("Validate() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidatorMatchesValidateCPF(t *testing.T) {
+	inputs := []string{"11144477735", "11144477734", "11111111111", "1234567890", "529.982.247-25"}
+	for _, input := range inputs {
+		for _, byLength := range []bool{false, true} {
+			v := NewValidator(WithLengthOnly(byLength))
+			if got, want := v.Validate(input), ValidateCPF(input, byLength); got != want {
+				t.Errorf("Validate(%q) with LengthOnly=%v = %v, ValidateCPF() = %v", input, byLength, got, want)
+			}
+		}
+	}
+}
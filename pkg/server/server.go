@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+// maxGenerateCount caps how many CPFs a single /generate request may ask for.
+const maxGenerateCount = 1000
+
+// errorResponse is the JSON body returned for malformed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates an HTTP server exposing the validate, format and generate
+// operations as JSON endpoints
+func NewServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           NewHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// NewHandler returns the HTTP handler serving the CPF endpoints
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /validate", handleValidate)
+	mux.HandleFunc("GET /format", handleFormat)
+	mux.HandleFunc("GET /generate", handleGenerate)
+	return mux
+}
+
+// handleValidate serves GET /validate?cpf=...
+func handleValidate(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("cpf")
+	if input == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing cpf parameter"})
+		return
+	}
+	writeJSON(w, http.StatusOK, cpf.ValidateProcessor(input))
+}
+
+// handleFormat serves GET /format?cpf=...
+func handleFormat(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("cpf")
+	if input == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing cpf parameter"})
+		return
+	}
+	result := cpf.FormatProcessor(input)
+	if result.Error != "" {
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleGenerate serves GET /generate?count=N&invalid=true&unformatted=true
+func handleGenerate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	count := 1
+	if countStr := query.Get("count"); countStr != "" {
+		n, err := strconv.Atoi(countStr)
+		if err != nil || n <= 0 || n > maxGenerateCount {
+			writeJSON(w, http.StatusBadRequest, errorResponse{
+				Error: fmt.Sprintf("invalid count value '%s' (must be between 1 and %d)", countStr, maxGenerateCount),
+			})
+			return
+		}
+		count = n
+	}
+
+	invalid, err := boolParam(query.Get("invalid"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid value for invalid parameter"})
+		return
+	}
+	unformatted, err := boolParam(query.Get("unformatted"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid value for unformatted parameter"})
+		return
+	}
+
+	results, err := cpf.GenerateCPFsJSON(count, !unformatted, invalid)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// boolParam parses an optional boolean query parameter
+func boolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
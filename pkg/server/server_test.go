@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diegopeixoto/cpf-cli-go/pkg/cpf"
+)
+
+func get(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s Content-Type = %q, want application/json", url, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s decoding body error = %v", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	ts := httptest.NewServer(NewServer(":0").Handler)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantValid  bool
+	}{
+		{"valid CPF", "?cpf=529.982.247-25", http.StatusOK, true},
+		{"invalid CPF", "?cpf=52998224726", http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result cpf.CPFResult
+			if status := get(t, ts.URL+"/validate"+tt.query, &result); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if result.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", result.Valid, tt.wantValid)
+			}
+		})
+	}
+
+	var errResp errorResponse
+	if status := get(t, ts.URL+"/validate", &errResp); status != http.StatusBadRequest {
+		t.Errorf("missing cpf status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestFormatEndpoint(t *testing.T) {
+	ts := httptest.NewServer(NewServer(":0").Handler)
+	defer ts.Close()
+
+	var result cpf.CPFResult
+	if status := get(t, ts.URL+"/format?cpf=52998224725", &result); status != http.StatusOK {
+		t.Errorf("status = %d, want %d", status, http.StatusOK)
+	}
+	if result.CPF != "529.982.247-25" {
+		t.Errorf("cpf = %q, want %q", result.CPF, "529.982.247-25")
+	}
+
+	result = cpf.CPFResult{}
+	if status := get(t, ts.URL+"/format?cpf=123", &result); status != http.StatusUnprocessableEntity {
+		t.Errorf("short cpf status = %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+	if result.Error == "" {
+		t.Error("short cpf expected an error message")
+	}
+}
+
+func TestGenerateEndpoint(t *testing.T) {
+	ts := httptest.NewServer(NewServer(":0").Handler)
+	defer ts.Close()
+
+	var results []cpf.CPFResult
+	if status := get(t, ts.URL+"/generate?count=3", &results); status != http.StatusOK {
+		t.Errorf("status = %d, want %d", status, http.StatusOK)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for _, r := range results {
+		if !cpf.ValidateCPF(r.CPF, false) {
+			t.Errorf("generated CPF %q is not valid", r.CPF)
+		}
+	}
+
+	results = nil
+	if status := get(t, ts.URL+"/generate?invalid=true&unformatted=true", &results); status != http.StatusOK {
+		t.Errorf("invalid status = %d, want %d", status, http.StatusOK)
+	}
+	if len(results) != 1 || len(results[0].CPF) != 11 {
+		t.Errorf("unformatted results = %+v, want one 11-digit CPF", results)
+	}
+
+	for _, query := range []string{"?count=0", "?count=abc", "?count=1001", "?invalid=maybe"} {
+		var errResp errorResponse
+		if status := get(t, ts.URL+"/generate"+query, &errResp); status != http.StatusBadRequest {
+			t.Errorf("generate%s status = %d, want %d", query, status, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/posthog/posthog-go"
+)
+
+// Sink receives the events recorded by Track.
+type Sink interface {
+	Send(Event) error
+}
+
+// PostHogSink sends events to a PostHog instance.
+type PostHogSink struct {
+	client posthog.Client
+}
+
+// NewPostHogSink creates a sink sending events to the PostHog instance at
+// endpoint. Events are buffered in memory and sent together in a single
+// /batch request when the sink is closed; the flush interval is far longer
+// than any CLI run so that a command's events are never split across
+// requests. Close waits for in-flight sends to finish.
+func NewPostHogSink(key, endpoint string) (*PostHogSink, error) {
+	client, err := posthog.NewWithConfig(key, posthog.Config{
+		Endpoint:  endpoint,
+		Transport: newTransport(),
+		Interval:  time.Hour,
+		BatchSize: posthog.DefaultBatchSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostHog client: %w", err)
+	}
+	return &PostHogSink{client: client}, nil
+}
+
+// Send enqueues the event for the next batch.
+func (s *PostHogSink) Send(e Event) error {
+	return s.client.Enqueue(capture(e))
+}
+
+// capture builds the PostHog message for an event.
+func capture(e Event) posthog.Capture {
+	properties := posthog.NewProperties()
+	properties.Set("command", e.Command)
+	properties.Set("success", e.Success)
+	properties.Set("os", e.OS)
+	properties.Set("arch", e.Arch)
+	properties.Set("version", e.Version)
+	properties.Set("timestamp", e.Timestamp)
+	if e.Error != "" {
+		properties.Set("error", e.Error)
+	}
+	for k, v := range e.Metadata {
+		properties.Set(k, v)
+	}
+
+	return posthog.Capture{
+		DistinctId: e.DistinctID,
+		Event:      "cli_command",
+		Timestamp:  e.Timestamp,
+		Properties: properties,
+	}
+}
+
+// Close sends the buffered events and shuts the client down.
+func (s *PostHogSink) Close() error {
+	return s.client.Close()
+}
+
+// DryRunSink writes each event to a writer as the JSON that would be sent to
+// PostHog, instead of sending it.
+type DryRunSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewDryRunSink creates a sink writing events to w.
+func NewDryRunSink(w io.Writer) *DryRunSink {
+	return &DryRunSink{w: w}
+}
+
+// Send writes the PostHog payload for the event.
+func (s *DryRunSink) Send(e Event) error {
+	msg := capture(e)
+	msg.Type = "capture"
+	data, err := json.MarshalIndent(msg.APIfy(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintln(s.w, string(data))
+	return err
+}
+
+// NoopSink discards every event.
+type NoopSink struct{}
+
+// Send discards the event.
+func (NoopSink) Send(Event) error {
+	return nil
+}
+
+// MemorySink keeps events in memory, which is useful in tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Send records the event.
+func (s *MemorySink) Send(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+// Events returns a copy of the recorded events.
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
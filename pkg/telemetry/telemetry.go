@@ -3,29 +3,40 @@ package telemetry
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"time"
 
-	"github.com/posthog/posthog-go"
+	"github.com/google/uuid"
 )
 
 // Config represents telemetry configuration
 type Config struct {
 	Enabled bool `json:"enabled"`
+	// Endpoint overrides the PostHog instance events are sent to, for
+	// self-hosted installations.
+	Endpoint string `json:"endpoint,omitempty"`
+	// ApiKey overrides the PostHog project API key set at build time.
+	ApiKey string `json:"api_key,omitempty"`
+	// DistinctID anonymously identifies the installation: a random UUID
+	// created on first run, unrelated to the machine or its user.
+	DistinctID string `json:"distinct_id,omitempty"`
 }
 
 // Event represents a telemetry event
 type Event struct {
-	Command   string            `json:"command"`
-	Success   bool              `json:"success"`
-	Error     string           `json:"error,omitempty"`
-	OS        string           `json:"os"`
-	Arch      string           `json:"arch"`
-	Version   string           `json:"version"`
-	Timestamp time.Time        `json:"timestamp"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
+	DistinctID string            `json:"distinct_id"`
+	Command    string            `json:"command"`
+	Success    bool              `json:"success"`
+	Error      string            `json:"error,omitempty"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	Version    string            `json:"version"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
 }
 
 var (
@@ -33,22 +44,27 @@ var (
 	configPath string
 	version    string // Will be set during initialization
 	apiKey     string // Will be set at build time
-	client     posthog.Client
+	sink       Sink
+	customSink bool // Set when the sink was injected with SetSink
+	suppressed bool // Set by Suppress for the current process only
+	previewing bool // Set by Preview for the current process only
 )
 
+// posthogEndpoint is the PostHog instance events are sent to
+const posthogEndpoint = "https://us.i.posthog.com"
+
+// SetSink makes Track send events to s instead of PostHog. Passing nil
+// restores the default PostHog sink on the next Initialize.
+func SetSink(s Sink) {
+	sink = s
+	customSink = s != nil
+}
+
 // Initialize sets up telemetry with the given version
 func Initialize(v string) error {
 	version = v
-
-	// Initialize PostHog client if we have an API key
-	if apiKey != "" {
-		var err error
-		client, err = posthog.NewWithConfig(apiKey, posthog.Config{
-			Endpoint: "https://us.i.posthog.com",
-		})
-		if err != nil {
-			return fmt.Errorf("failed to initialize PostHog client: %w", err)
-		}
+	if !customSink {
+		sink = nil
 	}
 
 	homeDir, err := os.UserHomeDir()
@@ -73,9 +89,43 @@ func Initialize(v string) error {
 		}
 	}
 
+	if config.DistinctID == "" {
+		config.DistinctID = uuid.NewString()
+		if err := saveConfig(); err != nil {
+			return fmt.Errorf("failed to save telemetry ID: %w", err)
+		}
+	}
+
+	// Send events to PostHog if we have an API key, unless a sink was injected
+	if !customSink {
+		if key := config.apiKey(); key != "" {
+			posthogSink, err := NewPostHogSink(key, config.endpoint())
+			if err != nil {
+				return err
+			}
+			sink = posthogSink
+		}
+	}
+
 	return nil
 }
 
+// endpoint returns the configured PostHog endpoint, or the built-in one
+func (c *Config) endpoint() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	return posthogEndpoint
+}
+
+// apiKey returns the configured PostHog API key, or the one set at build time
+func (c *Config) apiKey() string {
+	if c.ApiKey != "" {
+		return c.ApiKey
+	}
+	return apiKey
+}
+
 // loadConfig loads the telemetry configuration from disk
 func loadConfig() error {
 	data, err := os.ReadFile(configPath)
@@ -100,49 +150,115 @@ func SetEnabled(enabled bool) error {
 	return saveConfig()
 }
 
+// SetEndpoint saves the PostHog endpoint events are sent to. An empty
+// endpoint restores the built-in one. It takes effect on the next Initialize.
+func SetEndpoint(endpoint string) error {
+	config.Endpoint = endpoint
+	return saveConfig()
+}
+
+// SetAPIKey saves the PostHog API key events are sent with. An empty key
+// restores the one set at build time. It takes effect on the next Initialize.
+func SetAPIKey(key string) error {
+	config.ApiKey = key
+	return saveConfig()
+}
+
+// ResetID replaces the anonymous installation ID with a new random one and
+// returns it, so that later events can't be linked to earlier ones.
+func ResetID() (string, error) {
+	config.DistinctID = uuid.NewString()
+	if err := saveConfig(); err != nil {
+		return "", err
+	}
+	return config.DistinctID, nil
+}
+
+// Endpoint returns the PostHog endpoint events are sent to.
+func Endpoint() string {
+	if config == nil {
+		return posthogEndpoint
+	}
+	return config.endpoint()
+}
+
+// Suppress turns telemetry off, or back on, for the current process without
+// changing the saved configuration. It may be called before Initialize.
+func Suppress(s bool) {
+	suppressed = s
+}
+
+// Preview makes Track write events to w as the JSON that would be sent to
+// PostHog, without sending anything, whether or not telemetry is enabled.
+// Passing nil ends the preview and restores the default sink on the next
+// Initialize.
+func Preview(w io.Writer) {
+	previewing = w != nil
+	if w == nil {
+		SetSink(nil)
+		return
+	}
+	SetSink(NewDryRunSink(w))
+}
+
+// Suppressed reports whether telemetry was turned off with Suppress.
+func Suppressed() bool {
+	return suppressed
+}
+
 // IsEnabled returns whether telemetry is enabled
 func IsEnabled() bool {
-	return config != nil && config.Enabled && apiKey != "" && client != nil
+	if previewing {
+		return true
+	}
+	if suppressed || DisabledByEnv() {
+		return false
+	}
+	return config != nil && config.Enabled && sink != nil
+}
+
+// DisabledByEnv reports whether telemetry is forced off through the
+// environment, either with CPF_CLI_TELEMETRY=0 or DO_NOT_TRACK=1. The
+// environment takes precedence over the saved configuration.
+func DisabledByEnv() bool {
+	if v, err := strconv.ParseBool(os.Getenv("CPF_CLI_TELEMETRY")); err == nil && !v {
+		return true
+	}
+	if v, err := strconv.ParseBool(os.Getenv("DO_NOT_TRACK")); err == nil && v {
+		return true
+	}
+	return false
 }
 
-// Close closes the PostHog client
+// Close flushes and closes the sink, if it needs closing
 func Close() error {
-	if client != nil {
-		return client.Close()
+	if closer, ok := sink.(interface{ Close() error }); ok {
+		return closer.Close()
 	}
 	return nil
 }
 
-// Track sends a telemetry event if telemetry is enabled
+// Track records a telemetry event if telemetry is enabled. Nothing is
+// recorded when Initialize failed, even in preview mode.
 func Track(command string, success bool, err error, metadata map[string]string) {
-	if !IsEnabled() {
+	if !IsEnabled() || config == nil {
 		return
 	}
 
-	// Create a unique identifier for the installation
-	distinctId := fmt.Sprintf("%s-%s-%s", runtime.GOOS, runtime.GOARCH, version)
-
-	// Convert metadata to interface{} map
-	properties := make(map[string]interface{})
-	properties["command"] = command
-	properties["success"] = success
-	properties["os"] = runtime.GOOS
-	properties["arch"] = runtime.GOARCH
-	properties["version"] = version
-	properties["timestamp"] = time.Now().UTC()
-
-	if err != nil {
-		properties["error"] = err.Error()
+	event := Event{
+		DistinctID: config.DistinctID,
+		Command:    command,
+		Success:    success,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Version:    version,
+		Timestamp:  time.Now().UTC(),
+		Metadata:   metadata,
 	}
-
-	for k, v := range metadata {
-		properties[k] = v
+	if err != nil {
+		event.Error = err.Error()
 	}
 
-	// Send event asynchronously
-	client.Enqueue(posthog.Capture{
-		DistinctId: distinctId,
-		Event:      "cli_command",
-		Properties: properties,
-	})
-} 
\ No newline at end of file
+	// Telemetry must never get in the way of the command
+	_ = sink.Send(event)
+}
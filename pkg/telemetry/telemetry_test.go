@@ -0,0 +1,332 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// setupEnabled configures the package as if telemetry had been initialized
+// with an API key and the given saved setting.
+func setupEnabled(t *testing.T, enabled bool) {
+	t.Helper()
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+
+	useSink(t, &Config{Enabled: enabled}, &MemorySink{})
+}
+
+// useSink sets the package configuration and sink for the test duration.
+func useSink(t *testing.T, c *Config, s Sink) {
+	t.Helper()
+	prevConfig, prevSink, prevCustom := config, sink, customSink
+	config = c
+	SetSink(s)
+	t.Cleanup(func() {
+		config, sink, customSink = prevConfig, prevSink, prevCustom
+	})
+}
+
+func TestIsEnabledEnvOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		env      map[string]string
+		expected bool
+	}{
+		{"enabled without env", true, nil, true},
+		{"disabled without env", false, nil, false},
+		{"enabled with CPF_CLI_TELEMETRY=0", true, map[string]string{"CPF_CLI_TELEMETRY": "0"}, false},
+		{"enabled with CPF_CLI_TELEMETRY=false", true, map[string]string{"CPF_CLI_TELEMETRY": "false"}, false},
+		{"disabled with CPF_CLI_TELEMETRY=0", false, map[string]string{"CPF_CLI_TELEMETRY": "0"}, false},
+		{"enabled with CPF_CLI_TELEMETRY=1", true, map[string]string{"CPF_CLI_TELEMETRY": "1"}, true},
+		{"enabled with DO_NOT_TRACK=1", true, map[string]string{"DO_NOT_TRACK": "1"}, false},
+		{"disabled with DO_NOT_TRACK=1", false, map[string]string{"DO_NOT_TRACK": "1"}, false},
+		{"enabled with DO_NOT_TRACK=0", true, map[string]string{"DO_NOT_TRACK": "0"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEnabled(t, tt.enabled)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			if got := IsEnabled(); got != tt.expected {
+				t.Errorf("IsEnabled() = %v, want %v", got, tt.expected)
+			}
+			if config.Enabled != tt.enabled {
+				t.Errorf("config.Enabled changed to %v", config.Enabled)
+			}
+		})
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	setupEnabled(t, true)
+	t.Cleanup(func() { Suppress(false) })
+
+	Suppress(true)
+	if IsEnabled() {
+		t.Error("IsEnabled() = true while suppressed")
+	}
+	if !config.Enabled {
+		t.Error("Suppress changed the saved configuration")
+	}
+
+	Suppress(false)
+	if !IsEnabled() {
+		t.Error("IsEnabled() = false after lifting the suppression")
+	}
+}
+
+func TestTrackBatchesEventsUntilClose(t *testing.T) {
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+
+	var mu sync.Mutex
+	var payloads []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch/" {
+			t.Errorf("request path = %q, want /batch/", r.URL.Path)
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload error = %v", err)
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	posthogSink, err := NewPostHogSink("test-key", srv.URL)
+	if err != nil {
+		t.Fatalf("NewPostHogSink() error = %v", err)
+	}
+	useSink(t, &Config{Enabled: true, DistinctID: "test-id"}, posthogSink)
+
+	Track("validate", true, nil, nil)
+	Track("format", true, nil, nil)
+	Track("generate", false, errors.New("boom"), nil)
+
+	mu.Lock()
+	sentBeforeClose := len(payloads)
+	mu.Unlock()
+	if sentBeforeClose != 0 {
+		t.Errorf("%d requests sent before Close, want 0", sentBeforeClose)
+	}
+
+	if err := Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 1 {
+		t.Fatalf("server received %d requests, want a single batch", len(payloads))
+	}
+	batch, _ := payloads[0]["batch"].([]any)
+	if len(batch) != 3 {
+		t.Errorf("batch contains %d events, want 3", len(batch))
+	}
+}
+
+func TestTrackSendsEventsToSink(t *testing.T) {
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+	mem := &MemorySink{}
+	useSink(t, &Config{Enabled: true}, mem)
+	version = "1.2.3"
+
+	Track("validate", true, nil, map[string]string{"args": "52998224725"})
+	Track("format", false, errors.New("boom"), nil)
+
+	events := mem.Events()
+	if len(events) != 2 {
+		t.Fatalf("sink received %d events, want 2", len(events))
+	}
+	first, second := events[0], events[1]
+	if first.Command != "validate" || !first.Success || first.Error != "" || first.Metadata["args"] != "52998224725" {
+		t.Errorf("first event = %+v", first)
+	}
+	if second.Command != "format" || second.Success || second.Error != "boom" {
+		t.Errorf("second event = %+v", second)
+	}
+	if first.Version != "1.2.3" || first.OS == "" || first.Arch == "" || first.Timestamp.IsZero() {
+		t.Errorf("first event is missing environment details: %+v", first)
+	}
+
+	config.Enabled = false
+	Track("generate", true, nil, nil)
+	if n := len(mem.Events()); n != 2 {
+		t.Errorf("sink received %d events after disabling telemetry, want 2", n)
+	}
+}
+
+func TestNoopSink(t *testing.T) {
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+	useSink(t, &Config{Enabled: true}, NoopSink{})
+
+	Track("validate", true, nil, nil)
+	if err := Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+	useSink(t, &Config{Enabled: false, DistinctID: "test-id"}, nil)
+	version = "1.2.3"
+
+	var buf bytes.Buffer
+	Preview(&buf)
+	Track("validate", false, errors.New("boom"), map[string]string{"args": "123"})
+	Preview(nil)
+	Track("format", true, nil, nil)
+
+	var payload struct {
+		Type       string         `json:"type"`
+		Event      string         `json:"event"`
+		DistinctId string         `json:"distinct_id"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatalf("preview is not a single JSON payload: %v\n%s", err, buf.String())
+	}
+	if payload.Type != "capture" || payload.Event != "cli_command" || !strings.HasSuffix(payload.DistinctId, "test-id") {
+		t.Errorf("payload = %+v", payload)
+	}
+	props := payload.Properties
+	if props["command"] != "validate" || props["success"] != false || props["error"] != "boom" || props["args"] != "123" {
+		t.Errorf("payload properties = %v", props)
+	}
+	if IsEnabled() {
+		t.Error("IsEnabled() = true after the preview ended with telemetry disabled")
+	}
+}
+
+func TestPreviewWithoutConfig(t *testing.T) {
+	t.Setenv("HOME", "")
+	useSink(t, nil, nil)
+
+	var buf bytes.Buffer
+	Preview(&buf)
+	defer Preview(nil)
+	if err := Initialize("test"); err == nil {
+		t.Fatal("Initialize() error = nil without a home directory")
+	}
+	Track("validate", true, nil, nil)
+	if buf.Len() != 0 {
+		t.Errorf("preview without a config wrote %q", buf.String())
+	}
+}
+
+func TestInitializeUsesConfiguredEndpoint(t *testing.T) {
+	t.Setenv("CPF_CLI_TELEMETRY", "")
+	t.Setenv("DO_NOT_TRACK", "")
+
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			ApiKey string `json:"api_key"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload error = %v", err)
+		}
+		mu.Lock()
+		keys = append(keys, payload.ApiKey)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".cpf-cli"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := Config{Enabled: true, Endpoint: srv.URL, ApiKey: "self-hosted-key"}
+	data, _ := json.Marshal(saved)
+	if err := os.WriteFile(filepath.Join(home, ".cpf-cli", "telemetry.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prevConfig, prevSink, prevCustom, prevKey := config, sink, customSink, apiKey
+	apiKey = ""
+	SetSink(nil)
+	t.Cleanup(func() {
+		config, sink, customSink, apiKey = prevConfig, prevSink, prevCustom, prevKey
+	})
+
+	if err := Initialize("test"); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if Endpoint() != srv.URL {
+		t.Errorf("Endpoint() = %q, want %q", Endpoint(), srv.URL)
+	}
+	if _, ok := sink.(*PostHogSink); !ok {
+		t.Fatalf("sink = %T, want *PostHogSink", sink)
+	}
+
+	Track("validate", true, nil, nil)
+	if err := Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 1 || keys[0] != "self-hosted-key" {
+		t.Errorf("configured endpoint received %v, want one batch sent with the configured key", keys)
+	}
+}
+
+func TestInitializePersistsDistinctID(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	prevConfig, prevSink, prevCustom := config, sink, customSink
+	SetSink(NoopSink{})
+	t.Cleanup(func() {
+		config, sink, customSink = prevConfig, prevSink, prevCustom
+	})
+
+	if err := Initialize("test"); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	id := config.DistinctID
+	if _, err := uuid.Parse(id); err != nil {
+		t.Fatalf("DistinctID = %q, not a UUID: %v", id, err)
+	}
+
+	if err := Initialize("test"); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if config.DistinctID != id {
+		t.Errorf("DistinctID = %q after a second Initialize, want %q", config.DistinctID, id)
+	}
+
+	newID, err := ResetID()
+	if err != nil {
+		t.Fatalf("ResetID() error = %v", err)
+	}
+	if newID == id {
+		t.Errorf("ResetID() kept the ID %q", id)
+	}
+	if err := Initialize("test"); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if config.DistinctID != newID {
+		t.Errorf("DistinctID = %q after ResetID and Initialize, want %q", config.DistinctID, newID)
+	}
+}
@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	// requestTimeout bounds each attempt to deliver telemetry to PostHog
+	requestTimeout = 3 * time.Second
+	// retryBackoff is how long to wait before the single retry
+	retryBackoff = 200 * time.Millisecond
+)
+
+// retryTransport is the HTTP transport used by the PostHog client. Each
+// request gets a short timeout and a single retry on network errors or 5xx
+// responses, so a hung endpoint can never hold the CLI up for long.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+// newTransport returns the transport telemetry requests are sent through
+func newTransport() http.RoundTripper {
+	return &retryTransport{base: http.DefaultTransport}
+}
+
+// RoundTrip sends req, retrying once on transient failures
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.attempt(req)
+	if !transient(resp, err) {
+		return resp, err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	if req.Body != nil {
+		if req.GetBody == nil {
+			if err == nil {
+				return nil, fmt.Errorf("telemetry: server returned %s", resp.Status)
+			}
+			return nil, fmt.Errorf("telemetry request failed and cannot be retried: %w", err)
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+
+	select {
+	case <-time.After(retryBackoff):
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	return t.attempt(req)
+}
+
+// attempt sends req once, bounded by requestTimeout
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// transient reports whether a request outcome is worth retrying
+func transient(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
+
+// cancelBody releases the attempt's timeout context once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the attempt's context
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
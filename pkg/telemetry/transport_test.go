@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withFastRetries shortens the transport timings for the test duration
+func withFastRetries(t *testing.T) {
+	t.Helper()
+	prevTimeout, prevBackoff := requestTimeout, retryBackoff
+	requestTimeout, retryBackoff = 50*time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		requestTimeout, retryBackoff = prevTimeout, prevBackoff
+	})
+}
+
+func TestTransportRetriesAfterTimeout(t *testing.T) {
+	withFastRetries(t)
+
+	var calls atomic.Int32
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		if calls.Add(1) == 1 {
+			// Hang until the client gives up on the first attempt
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	httpClient := &http.Client{Transport: newTransport()}
+	resp, err := httpClient.Post(srv.URL+"/batch/", "application/json", strings.NewReader(`{"batch":[]}`))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 || bodies[1] != `{"batch":[]}` {
+		t.Errorf("retried request bodies = %q, want the original body resent", bodies)
+	}
+}
+
+func TestTransportRetriesOnServerError(t *testing.T) {
+	withFastRetries(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	httpClient := &http.Client{Transport: newTransport()}
+	resp, err := httpClient.Post(srv.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server received %d requests, want exactly one retry", got)
+	}
+}
+
+func TestTransportDoesNotRetryClientErrors(t *testing.T) {
+	withFastRetries(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	httpClient := &http.Client{Transport: newTransport()}
+	resp, err := httpClient.Post(srv.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestTransportServerErrorWithoutRewindableBody(t *testing.T) {
+	withFastRetries(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// A body of an unknown reader type can't be rewound for a retry
+	req, err := http.NewRequest(http.MethodPost, srv.URL, io.MultiReader(strings.NewReader("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newTransport().RoundTrip(req)
+	if err == nil || err.Error() != "telemetry: server returned 503 Service Unavailable" {
+		t.Errorf("RoundTrip() error = %v, want the server status", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}